@@ -0,0 +1,108 @@
+package ecs
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// MetaOf returns the ComponentMeta of the Component c whose data is of type C.
+// The ComponentMeta of a tag is simply ComponentMeta{Component: c}.
+func MetaOf[C any](c Component) ComponentMeta {
+	return ComponentMeta{
+		Component: c,
+		TableType: reflect.TypeFor[*Table[C]](),
+	}
+}
+
+// NewEntities creates n entities with the Components in types, and their data are zero values.
+//
+// Unlike calling NewEntity and SetComp for each entity,
+// the entities are put into the target archetype directly without walking through the intermediate ones,
+// and the columns of the archetype are grown only once.
+//
+// This function panics if a Component appears more than once in types.
+func (w *World) NewEntities(n int, types ...ComponentMeta) (entities []Entity) {
+	t := slices.SortedFunc(slices.Values(types), func(a, b ComponentMeta) int {
+		return cmp.Compare(a.Component, b.Component)
+	})
+	for i := 1; i < len(t); i++ {
+		if t[i].Component == t[i-1].Component {
+			panic(fmt.Sprintf("ecs: duplicate Component %s in NewEntities", w.label(Entity(t[i].Component))))
+		}
+	}
+	defer w.begin("NewEntities")()
+	a := w.archetype(t)
+	w.touch(a)
+
+	for _, s := range a.Comps {
+		if s != nil {
+			s.grow(n)
+		}
+	}
+	a.entities = slices.Grow(a.entities, n)
+	a.records = slices.Grow(a.records, n)
+
+	entities = make([]Entity, n)
+	records := make([]EntityRecord, n)
+	for i := range entities {
+		e := Entity(w.get())
+		r := &records[i]
+		r.AT = a
+		r.Row = a.push(e, r)
+		w.Entities[e] = r
+		entities[i] = e
+		if w.journal != nil {
+			w.journal.created("NewEntities", e)
+		}
+	}
+	return
+}
+
+// SetCompBulk sets the Component of each Entity in entities to the corresponding element of data.
+//
+// It does the same as calling SetComp for each Entity,
+// but the target archetype is looked up only once for the entities coming from the same archetype.
+// This function panics if data is shorter than entities.
+func (w *World) SetCompBulk[C any](entities []Entity, c Component, data []C) {
+	defer w.begin("SetCompBulk")()
+	storeType := reflect.TypeFor[*Table[C]]()
+	var src, target *Archetype
+	var targetCol int
+	for i, e := range entities {
+		if w.journal != nil {
+			w.journal.changing("SetCompBulk", e, c)
+		}
+		rec := w.Entities[e]
+		// If the archetype of e already contains c.
+		// Override the data and continue.
+		if col, ok := w.Components[c][rec.AT]; ok {
+			w.touch(rec.AT)
+			(*rec.AT.Comps[col].(*Table[C]))[rec.Row] = data[i]
+			rec.AT.changed[col]++
+			continue
+		}
+		if rec.AT != src {
+			src, target = rec.AT, w.addTarget(rec.AT, c, storeType)
+			targetCol = w.Components[c][target]
+		}
+		// Move entity to the new archetype
+		row := w.moveEntity(e, target, rec, rec.AT.Types)
+		if rec.Row != len(rec.AT.entities) {
+			rec.AT.records[rec.Row].Row = rec.Row
+		}
+		target.Comps[targetCol].(*Table[C]).append(data[i])
+
+		rec.AT = target
+		rec.Row = row
+	}
+}
+
+// DelEntities deletes all the entities.
+func (w *World) DelEntities(entities ...Entity) {
+	defer w.begin("DelEntities")()
+	for _, e := range entities {
+		w.DelEntity(e)
+	}
+}
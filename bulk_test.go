@@ -0,0 +1,131 @@
+package ecs
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWorld_NewEntities(t *testing.T) {
+	type Position struct{ x, y float64 }
+
+	w := NewWorld()
+	position := w.NewComponent()
+	velocity := w.NewComponent()
+	walking := w.NewComponent()
+
+	e := w.NewEntity()
+	w.SetComp(e, position, Position{1, 2})
+	w.SetComp(e, velocity, Position{3, 4})
+	w.AddComp(e, walking)
+	tableCount := len(w.Archetypes)
+
+	entities := w.NewEntities(100, MetaOf[Position](velocity), ComponentMeta{Component: walking}, MetaOf[Position](position))
+	if len(w.Archetypes) != tableCount {
+		t.Errorf("NewEntities creates extra archetypes")
+	}
+	for _, e := range entities {
+		if !w.HasComp(e, position) || !w.HasComp(e, velocity) || !w.HasComp(e, walking) {
+			t.Fatalf("entity %v doesn't have all the components", e)
+		}
+		if p := w.GetComp[Position](e, position); *p != (Position{}) {
+			t.Fatalf("entity %v has non-zero data %v", e, *p)
+		}
+	}
+
+	// The new entities work as usual
+	w.SetComp(entities[10], position, Position{5, 6})
+	w.DelComp(entities[10], velocity)
+	if p := w.GetComp[Position](entities[10], position); *p != (Position{5, 6}) {
+		t.Errorf("get: %v, want: %v", *p, Position{5, 6})
+	}
+	if p := w.GetComp[Position](e, position); *p != (Position{1, 2}) {
+		t.Errorf("get: %v, want: %v", *p, Position{1, 2})
+	}
+}
+
+func TestWorld_NewEntities_duplicate(t *testing.T) {
+	w := NewWorld()
+	c := w.NewComponent()
+	defer func() {
+		if recover() == nil {
+			t.Error("NewEntities doesn't panic on duplicate Components")
+		}
+		if err := w.CheckInvariants(); err != nil {
+			t.Error(err)
+		}
+	}()
+	w.NewEntities(2, MetaOf[int](c), MetaOf[int](c))
+}
+
+func TestWorld_SetCompBulk(t *testing.T) {
+	w := NewWorld()
+	c1 := w.NewComponent()
+	c2 := w.NewComponent()
+
+	var entities [10]Entity
+	for i := range entities {
+		entities[i] = w.NewEntity()
+	}
+	for _, e := range entities[5:] {
+		w.AddComp(e, c1)
+	}
+
+	data := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	w.SetCompBulk(entities[:], c2, data)
+	// Override the existing data
+	w.SetCompBulk(entities[:3], c2, []int{10, 11, 12})
+
+	var result []int
+	w.Query(QueryAll(c2), func(entities []Entity, data []any) {
+		result = append(result, *data[0].(*[]int)...)
+	})
+	sort.Ints(result)
+	if want := []int{3, 4, 5, 6, 7, 8, 9, 10, 11, 12}; !reflect.DeepEqual(result, want) {
+		t.Errorf("get: %v, want: %v", result, want)
+	}
+	for i, e := range entities[3:] {
+		if got := *w.GetComp[int](e, c2); got != i+3 {
+			t.Errorf("get: %v, want: %v", got, i+3)
+		}
+	}
+
+	w.DelEntities(entities[:5]...)
+	result = result[:0]
+	w.Query(QueryAll(c2), func(entities []Entity, data []any) {
+		result = append(result, *data[0].(*[]int)...)
+	})
+	sort.Ints(result)
+	if want := []int{5, 6, 7, 8, 9}; !reflect.DeepEqual(result, want) {
+		t.Errorf("get: %v, want: %v", result, want)
+	}
+}
+
+func BenchmarkWorld_NewEntities(b *testing.B) {
+	const EntityCount = 100_000
+	const ComponentCount = 8
+
+	w := NewWorld()
+	var components [ComponentCount]Component
+	var types [ComponentCount]ComponentMeta
+	for i := range components {
+		components[i] = w.NewComponent()
+		types[i] = MetaOf[int](components[i])
+	}
+
+	b.Run("one by one", func(b *testing.B) {
+		for b.Loop() {
+			for range EntityCount {
+				e := w.NewEntity()
+				for _, c := range components {
+					w.SetComp(e, c, 0)
+				}
+			}
+		}
+	})
+	b.Run("bulk", func(b *testing.B) {
+		for b.Loop() {
+			w.NewEntities(EntityCount, types[:]...)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package ecs
+
+import "sort"
+
+// Cascade makes the CachedQuery iterate its matched archetypes in the order of their depth
+// in the hierarchy of the relationship rel, such as ChildOf.
+// The entities without the relationship come first, then their children, grandchildren and so on.
+//
+// This allows the systems like transform propagation to be done in one pass,
+// where each entity reads the data of its parent which is already updated.
+// The order is checked and fixed each time the query is run.
+func Cascade(rel Component) CacheOption {
+	return func(q *CachedQuery) {
+		q.cascade = &rel
+	}
+}
+
+// sortByDepth orders the tables by their depth, if the query is created with Cascade.
+func (q *CachedQuery) sortByDepth() {
+	if q.cascade == nil {
+		return
+	}
+	// Only the active tables are iterated, so only they need to be sorted.
+	s := depthSorter{
+		q:      q,
+		depths: make([]int, q.active),
+	}
+	memo := make(map[*Archetype]int)
+	for i, a := range q.tables[:q.active] {
+		s.depths[i] = q.world.depth(a, *q.cascade, memo)
+	}
+	if sort.IsSorted(s) {
+		return
+	}
+	sort.Stable(s)
+
+	// Keep the groups in the same order.
+	if q.groupBy != nil {
+		clear(q.groups)
+		for _, a := range q.tables {
+			if key, ok := q.groupBy(q.world, a.Types); ok {
+				q.groups[key] = append(q.groups[key], a)
+			}
+		}
+	}
+}
+
+// depth returns the depth of the entities in archetype a, in the hierarchy of the relationship rel.
+// The memo records the depths already known.
+func (w *World) depth(a *Archetype, rel Component, memo map[*Archetype]int) int {
+	if d, ok := memo[a]; ok {
+		return d
+	}
+	memo[a] = 0 // In case of cycles
+	d := 0
+	for _, t := range a.Types {
+		if p, ok := w.pairOf[t.Component]; ok && p.Rel == rel {
+			if rec, ok := w.Entities[p.Target]; ok {
+				d = max(d, w.depth(rec.AT, rel, memo)+1)
+			}
+		}
+	}
+	memo[a] = d
+	return d
+}
+
+// depthSorter sorts the tables of a CachedQuery by their depths.
+type depthSorter struct {
+	q      *CachedQuery
+	depths []int
+}
+
+func (s depthSorter) Len() int           { return len(s.depths) }
+func (s depthSorter) Less(i, j int) bool { return s.depths[i] < s.depths[j] }
+func (s depthSorter) Swap(i, j int) {
+	s.depths[i], s.depths[j] = s.depths[j], s.depths[i]
+	s.q.swap(i, j)
+}
@@ -0,0 +1,71 @@
+package ecs
+
+import "testing"
+
+func TestCascade(t *testing.T) {
+	type Transform struct{ local, world int }
+
+	w := NewWorld()
+	transform := w.NewComponent()
+	childOf := w.NewComponent()
+
+	// Create the deepest entities first,
+	// so that their archetypes are created before their parents'.
+	root := w.NewEntity()
+	entities := []Entity{root}
+	parents := []Entity{root}
+	for depth := 1; depth <= 4; depth++ {
+		var next []Entity
+		for _, parent := range parents {
+			for range 2 {
+				e := w.NewEntity()
+				next = append(next, e)
+				entities = append(entities, e)
+				w.AddComp(e, w.Pair(childOf, parent))
+			}
+		}
+		parents = next
+	}
+	for i := len(entities) - 1; i >= 0; i-- {
+		w.SetComp(entities[i], transform, Transform{local: 1})
+	}
+
+	q := w.Cache(QueryAll(transform), Cascade(childOf))
+	propagate := func() {
+		q.Run(func(entities []Entity, data []any) {
+			transforms := *data[0].(*[]Transform)
+			for i, e := range entities {
+				transforms[i].world = transforms[i].local
+				for _, parent := range w.Targets(e, childOf) {
+					transforms[i].world += w.GetComp[Transform](parent, transform).world
+				}
+			}
+		})
+	}
+	propagate()
+
+	// The world transform equals to the depth plus 1.
+	check := func(t *testing.T) {
+		t.Helper()
+		var depthOf func(e Entity) int
+		depthOf = func(e Entity) int {
+			for _, parent := range w.Targets(e, childOf) {
+				return depthOf(parent) + 1
+			}
+			return 0
+		}
+		for _, e := range entities {
+			if got, want := w.GetComp[Transform](e, transform).world, depthOf(e)+1; got != want {
+				t.Errorf("world transform of %v: %v, want: %v", e, got, want)
+			}
+		}
+	}
+	check(t)
+
+	// Reparent a subtree to a deeper entity
+	leaf := entities[len(entities)-1]
+	w.DelComp(entities[1], w.Pair(childOf, root))
+	w.AddComp(entities[1], w.Pair(childOf, leaf))
+	propagate()
+	check(t)
+}
@@ -0,0 +1,174 @@
+package ecs
+
+// CloneOption configures World.Clone.
+type CloneOption func(*cloner)
+
+// CloneChildren makes the entities related to the cloned Entity by rel cloned too, recursively.
+// The cloned children are related to the clone instead.
+//
+// For example, with CloneChildren(ChildOf), the entities with (ChildOf, e) are cloned when cloning e,
+// and their clones have (ChildOf, clone of e).
+func CloneChildren(rel Component) CloneOption {
+	return func(c *cloner) {
+		c.children = append(c.children, rel)
+	}
+}
+
+// CloneTargets makes the targets of the cloned Entity's relationships of kind rel cloned too, recursively.
+// The clone is related to the cloned targets instead.
+//
+// Without this option, the clone shares the targets with the original Entity.
+func CloneTargets(rel Component) CloneOption {
+	return func(c *cloner) {
+		c.targets = append(c.targets, rel)
+	}
+}
+
+// CloneInto makes the clones created in the World dst.
+//
+// Since the same Component has different ids in different worlds,
+// comps maps the Components of the source World to the Components of dst.
+// The missing Components are created in dst, and are recorded in comps,
+// so the same map can be reused for the following clones.
+//
+// The relationships to the targets that are neither cloned nor Components are dropped,
+// because the targets don't exist in dst.
+func CloneInto(dst *World, comps map[Component]Component) CloneOption {
+	return func(c *cloner) {
+		c.dst = dst
+		c.comps = comps
+	}
+}
+
+// Clone creates a new Entity with the same Components and data as the Entity e,
+// and returns the new Entity.
+// The data is copied as is, so the pointers or slices in the data are shared by the clones.
+//
+// The disabled states of the Entity and its Components are cloned too.
+func (w *World) Clone(e Entity, opts ...CloneOption) Entity {
+	c := cloner{
+		src:    w,
+		dst:    w,
+		cloned: make(map[Entity]Entity),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.dst != w && c.comps == nil {
+		c.comps = make(map[Component]Component)
+	}
+	defer c.dst.begin("Clone")()
+	return c.clone(e)
+}
+
+// cloner holds the states of a World.Clone call.
+type cloner struct {
+	src, dst *World
+	children []Component
+	targets  []Component
+
+	// Maps the Components of src to dst. It's nil if src == dst.
+	comps map[Component]Component
+	// The already cloned entities and their clones.
+	cloned map[Entity]Entity
+}
+
+func (c *cloner) clone(e Entity) (clone Entity) {
+	clone = Entity(c.dst.get())
+	c.cloned[e] = clone
+
+	rec := c.src.Entities[e]
+	// Clone the targets first, so that we know the new targets when mapping the pairs.
+	for _, rel := range c.targets {
+		for _, target := range c.src.Targets(e, rel) {
+			if _, ok := c.cloned[target]; !ok {
+				c.clone(target)
+			}
+		}
+	}
+
+	// Map the Components to the dst world.
+	types := make(Types, 0, len(rec.AT.Types))
+	for _, t := range rec.AT.Types {
+		if comp, ok := c.component(t.Component); ok {
+			types = append(types, ComponentMeta{Component: comp, TableType: t.TableType})
+		}
+	}
+
+	a := c.dst.archetype(types)
+	r := &EntityRecord{AT: a}
+	c.dst.touch(a)
+	r.Row = a.push(clone, r)
+	c.dst.Entities[clone] = r
+	if c.dst.journal != nil {
+		c.dst.journal.created("Clone", clone)
+	}
+
+	// Copy the data
+	if rec.AT.disabled.has(rec.Row) {
+		a.disabled.set(r.Row)
+	}
+	for i, t := range rec.AT.Types {
+		comp, ok := c.component(t.Component)
+		if !ok {
+			continue
+		}
+		j := a.index(comp)
+		if s := rec.AT.Comps[i]; s != nil {
+			a.Comps[j].appendFrom(s, rec.Row)
+		}
+		if rec.AT.toggles[i].has(rec.Row) {
+			a.toggles[j].set(r.Row)
+		}
+	}
+
+	for _, rel := range c.children {
+		for _, child := range c.src.Sources(rel, e) {
+			if _, ok := c.cloned[child]; !ok {
+				c.clone(child)
+			}
+		}
+	}
+	return
+}
+
+// component maps the Component of src to dst.
+// The relationships are mapped to the same kind of relationships to the mapped targets.
+// If the Component can't be mapped, ok will be false.
+func (c *cloner) component(comp Component) (_ Component, ok bool) {
+	if p, isPair := c.src.pairOf[comp]; isPair {
+		rel, ok := c.component(p.Rel)
+		if !ok {
+			return 0, false
+		}
+		target, ok := c.entity(p.Target)
+		if !ok {
+			return 0, false
+		}
+		return c.dst.Pair(rel, target), true
+	}
+	if c.comps == nil {
+		return comp, true
+	}
+	if mapped, ok := c.comps[comp]; ok {
+		return mapped, true
+	}
+	mapped := c.dst.NewComponent()
+	c.comps[comp] = mapped
+	return mapped, true
+}
+
+// entity maps the Entity of src to dst.
+func (c *cloner) entity(e Entity) (_ Entity, ok bool) {
+	if clone, ok := c.cloned[e]; ok {
+		return clone, true
+	}
+	if c.comps == nil {
+		return e, true
+	}
+	if _, isComp := c.src.Components[Component(e)]; isComp {
+		comp, ok := c.component(Component(e))
+		return Entity(comp), ok
+	}
+	return 0, false
+}
@@ -0,0 +1,120 @@
+package ecs
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWorld_Clone(t *testing.T) {
+	type Position struct{ x, y float64 }
+
+	w := NewWorld()
+	position := w.NewComponent()
+	walking := w.NewComponent()
+	health := w.NewComponent()
+
+	e := w.NewEntity()
+	w.SetComp(e, position, Position{1, 2})
+	w.SetComp(e, health, 100)
+	w.AddComp(e, walking)
+	w.DisableComp(e, health)
+
+	clone := w.Clone(e)
+	if clone == e {
+		t.Fatal("the clone is the same entity")
+	}
+	if !w.HasComp(clone, walking) || !w.HasComp(clone, position) {
+		t.Error("the clone doesn't have all components")
+	}
+	if p := w.GetComp[Position](clone, position); *p != (Position{1, 2}) {
+		t.Errorf("get: %v, want: %v", *p, Position{1, 2})
+	}
+	if w.IsCompEnabled(clone, health) {
+		t.Error("the disabled component is enabled in the clone")
+	}
+
+	// The clone has its own data
+	w.SetComp(clone, position, Position{3, 4})
+	if p := w.GetComp[Position](e, position); *p != (Position{1, 2}) {
+		t.Errorf("get: %v, want: %v", *p, Position{1, 2})
+	}
+}
+
+func TestWorld_Clone_relationships(t *testing.T) {
+	w := NewWorld()
+	childOf := w.NewComponent()
+	equips := w.NewComponent()
+	name := w.NewComponent()
+
+	newEntity := func(n string) Entity {
+		e := w.NewEntity()
+		w.SetComp(e, name, n)
+		return e
+	}
+	unit := newEntity("unit")
+	sword := newEntity("sword")
+	arm := newEntity("arm")
+	hand := newEntity("hand")
+	w.AddComp(unit, w.Pair(equips, sword))
+	w.AddComp(arm, w.Pair(childOf, unit))
+	w.AddComp(hand, w.Pair(childOf, arm))
+
+	nameOf := func(e Entity) string { return *w.GetComp[string](e, name) }
+
+	// Without options, the relationships are shared.
+	clone := w.Clone(unit)
+	if targets := w.Targets(clone, equips); !slices.Equal(targets, []Entity{sword}) {
+		t.Errorf("targets of clone: %v, want: %v", targets, []Entity{sword})
+	}
+	if children := w.Sources(childOf, clone); len(children) != 0 {
+		t.Errorf("children of clone: %v, want none", children)
+	}
+
+	clone = w.Clone(unit, CloneChildren(childOf), CloneTargets(equips))
+	targets := w.Targets(clone, equips)
+	if len(targets) != 1 || targets[0] == sword || nameOf(targets[0]) != "sword" {
+		t.Errorf("the target isn't cloned: %v", targets)
+	}
+	children := w.Sources(childOf, clone)
+	if len(children) != 1 || children[0] == arm || nameOf(children[0]) != "arm" {
+		t.Fatalf("the child isn't cloned: %v", children)
+	}
+	grandchildren := w.Sources(childOf, children[0])
+	if len(grandchildren) != 1 || grandchildren[0] == hand || nameOf(grandchildren[0]) != "hand" {
+		t.Fatalf("the grandchild isn't cloned: %v", grandchildren)
+	}
+	if children := w.Sources(childOf, unit); !slices.Equal(children, []Entity{arm}) {
+		t.Errorf("children of the original: %v, want: %v", children, []Entity{arm})
+	}
+}
+
+func TestWorld_Clone_into(t *testing.T) {
+	src := NewWorld()
+	childOf := src.NewComponent()
+	health := src.NewComponent()
+	parent := src.NewEntity()
+	child := src.NewEntity()
+	src.SetComp(parent, health, 10)
+	src.SetComp(child, health, 20)
+	src.AddComp(child, src.Pair(childOf, parent))
+
+	dst := NewWorld()
+	dstHealth := dst.NewComponent()
+	comps := map[Component]Component{health: dstHealth}
+	clone := src.Clone(parent, CloneInto(dst, comps), CloneChildren(childOf))
+
+	if got := dst.GetComp[int](clone, dstHealth); got == nil || *got != 10 {
+		t.Errorf("get: %v, want: 10", got)
+	}
+	dstChildOf, ok := comps[childOf]
+	if !ok {
+		t.Fatal("the relationship isn't created in dst")
+	}
+	children := dst.Sources(dstChildOf, clone)
+	if len(children) != 1 {
+		t.Fatalf("children of clone: %v", children)
+	}
+	if got := dst.GetComp[int](children[0], dstHealth); got == nil || *got != 20 {
+		t.Errorf("get: %v, want: 20", got)
+	}
+}
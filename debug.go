@@ -1,21 +1,30 @@
 package ecs
 
 import (
+	"fmt"
+	"io"
+	"slices"
 	"sort"
 	"strings"
 )
 
+// Type returns the names of the Components of the Entity, separated by commas.
+// The name of a Component is its data of the nameComp if it has,
+// otherwise the name of its data type, or the label given by World.SetName for tags.
 func (w *World) Type(e Entity, nameComp Component) string {
 	var sb strings.Builder
 	rec := w.Entities[e]
 	compNames := make([]string, len(rec.AT.Types))
 	for i, v := range rec.AT.Types {
-		switch name := w.GetComp[string](Entity(v.Component), nameComp); name {
-		case nil:
+		switch name := w.GetComp[string](Entity(v.Component), nameComp); {
+		case name != nil:
+			compNames[i] = *name
+		case v.TableType != nil:
 			// type of v.TableType has to be `*Table[T]` which .Elem is `Table[T]` which .Elem is `T`
 			compNames[i] = v.TableType.Elem().Elem().Name()
 		default:
-			compNames[i] = *name
+			// Tags don't have a data type
+			compNames[i] = w.label(Entity(v.Component))
 		}
 	}
 	sort.Strings(compNames)
@@ -27,3 +36,96 @@ func (w *World) Type(e Entity, nameComp Component) string {
 	}
 	return sb.String()
 }
+
+// Describe returns a human-readable description of the Entity,
+// including its id, name, Components with their values, and relationships.
+// For example:
+//
+//	#12 "hero"
+//	  Position: {X:1 Y:2}
+//	  Player
+//	  (ChildOf, "scene")
+//
+// The values are formatted by %+v. The Components and entities without names are shown by their ids.
+func (w *World) Describe(e Entity) string {
+	var sb strings.Builder
+	w.describe(&sb, e)
+	return sb.String()
+}
+
+func (w *World) describe(sb *strings.Builder, e Entity) {
+	fmt.Fprintf(sb, "#%d", e)
+	if name, ok := w.nameOf[e]; ok {
+		fmt.Fprintf(sb, " %q", name)
+	}
+	rec, ok := w.Entities[e]
+	if !ok {
+		sb.WriteString(" (deleted)\n")
+		return
+	}
+	if rec.AT.disabled.has(rec.Row) {
+		sb.WriteString(" (disabled)")
+	}
+	sb.WriteByte('\n')
+
+	// The relationships are shown after the Components.
+	types := slices.Clone(rec.AT.Types)
+	slices.SortStableFunc(types, func(a, b ComponentMeta) int {
+		_, isPairA := w.pairOf[a.Component]
+		_, isPairB := w.pairOf[b.Component]
+		switch {
+		case !isPairA && isPairB:
+			return -1
+		case isPairA && !isPairB:
+			return 1
+		}
+		return 0
+	})
+	for _, t := range types {
+		sb.WriteString("  ")
+		if p, ok := w.pairOf[t.Component]; ok {
+			fmt.Fprintf(sb, "(%s, %s)", w.label(Entity(p.Rel)), w.entityLabel(p.Target))
+		} else {
+			sb.WriteString(w.label(Entity(t.Component)))
+		}
+		if t.TableType != nil {
+			fmt.Fprintf(sb, ": %+v", rec.AT.Comps[w.Components[t.Component][rec.AT]].Get(rec.Row))
+		}
+		if !w.IsCompEnabled(e, t.Component) {
+			sb.WriteString(" (disabled)")
+		}
+		sb.WriteByte('\n')
+	}
+}
+
+// entityLabel returns the quoted name of the Entity, or #id if it doesn't have one.
+func (w *World) entityLabel(e Entity) string {
+	if name, ok := w.nameOf[e]; ok {
+		return fmt.Sprintf("%q", name)
+	}
+	return fmt.Sprintf("#%d", e)
+}
+
+// Dump writes the descriptions of the entities matched by the filter in the order of their ids,
+// as World.Describe does, which is useful in the messages of test failures.
+// If the filter is nil, all entities are written, including the disabled ones.
+func (w *World) Dump(out io.Writer, f Filter) error {
+	var entities []Entity
+	if f == nil {
+		for e := range w.Entities {
+			entities = append(entities, e)
+		}
+	} else {
+		for e := range w.Iter(f) {
+			entities = append(entities, e)
+		}
+	}
+	slices.Sort(entities)
+
+	var sb strings.Builder
+	for _, e := range entities {
+		w.describe(&sb, e)
+	}
+	_, err := io.WriteString(out, sb.String())
+	return err
+}
@@ -0,0 +1,59 @@
+package ecs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWorld_Describe(t *testing.T) {
+	type Position struct{ X, Y int }
+
+	w := NewWorld()
+	position := w.NewComponent()
+	w.SetName(Entity(position), "Position")
+	player := w.NewComponent()
+	w.SetName(Entity(player), "Player")
+	childOf := w.NewComponent()
+	w.SetName(Entity(childOf), "ChildOf")
+	unnamed := w.NewComponent()
+
+	scene := w.NewEntity()
+	w.SetName(scene, "scene")
+	hero := w.NewEntity()
+	w.SetName(hero, "hero")
+	w.AddComp(hero, w.Pair(childOf, scene))
+	w.SetComp(hero, position, Position{1, 2})
+	w.AddComp(hero, player)
+	w.AddComp(hero, unnamed)
+	w.DisableComp(hero, player)
+
+	want := `#5 "hero"
+  Position: {X:1 Y:2}
+  Player (disabled)
+  #3
+  (ChildOf, "scene")
+`
+	if got := w.Describe(hero); got != want {
+		t.Errorf("Describe get:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The tags don't make Type panic.
+	if got := w.Type(hero, w.NewComponent()); got != "#3, (ChildOf, scene), Player, Position" {
+		t.Errorf("Type get: %q", got)
+	}
+
+	var sb strings.Builder
+	if err := w.Dump(&sb, QueryAll(position)); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != want {
+		t.Errorf("Dump get:\n%s\nwant:\n%s", sb.String(), want)
+	}
+	sb.Reset()
+	if err := w.Dump(&sb, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(sb.String(), "\n#"); got != len(w.Entities)-1 {
+		t.Errorf("Dump(nil) wrote %d entities, want: %d", got+1, len(w.Entities))
+	}
+}
@@ -0,0 +1,313 @@
+// Package ecshttp serves a World over HTTP as JSON, for inspecting and editing a running World.
+//
+// The endpoints are:
+//
+//	GET    /entities                         list the entities
+//	GET    /entities/{id}                    get the Components of an entity with their values
+//	PUT    /entities/{id}/components/{comp}  set a Component, the body is its value in JSON, or empty for a tag
+//	DELETE /entities/{id}/components/{comp}  remove a Component
+//	GET    /query?q=...                      run a query parsed by World.ParseQuery
+//	GET    /archetypes                       list the archetypes
+//	GET    /systems                          list the systems
+//
+// The {id} and {comp} are the ids of entities, or their names given by World.SetName.
+//
+// The World is read holding the read lock of the SyncWorld, while the changes are recorded into a Stage,
+// and applied at the next SyncWorld.Sync, which is the sync point of the game loop.
+// So the mutating endpoints respond 202 Accepted.
+package ecshttp
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/Tnze/go-ecs"
+)
+
+// Handler returns the http.Handler serving the World guarded by s.
+//
+// It's not registered anywhere by default. To use it, mount it to a local address, for example:
+//
+//	http.Handle("/ecs/", http.StripPrefix("/ecs", ecshttp.Handler(s)))
+func Handler(s *ecs.SyncWorld) http.Handler {
+	h := handler{s}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /entities", h.entities)
+	mux.HandleFunc("GET /entities/{id}", h.entity)
+	mux.HandleFunc("PUT /entities/{id}/components/{comp}", h.setComp)
+	mux.HandleFunc("DELETE /entities/{id}/components/{comp}", h.delComp)
+	mux.HandleFunc("GET /query", h.query)
+	mux.HandleFunc("GET /archetypes", h.archetypes)
+	mux.HandleFunc("GET /systems", h.systems)
+	return mux
+}
+
+type handler struct {
+	sync *ecs.SyncWorld
+}
+
+// Entity is the JSON form of an entity.
+type Entity struct {
+	ID         ecs.Entity  `json:"id"`
+	Name       string      `json:"name,omitempty"`
+	Components []Component `json:"components,omitempty"`
+}
+
+// Component is the JSON form of a Component of an entity.
+// The Value is omitted for tags.
+type Component struct {
+	ID    ecs.Component `json:"id"`
+	Name  string        `json:"name,omitempty"`
+	Value any           `json:"value,omitempty"`
+}
+
+// Archetype is the JSON form of an archetype.
+type Archetype struct {
+	Components []Component `json:"components"`
+	Entities   int         `json:"entities"`
+}
+
+// System is the JSON form of a System.
+type System struct {
+	Name     string `json:"name"`
+	Entities int    `json:"entities"` // The number of entities matched by its query.
+}
+
+func (h handler) entities(rw http.ResponseWriter, r *http.Request) {
+	var entities []Entity
+	h.sync.Read(func(w *ecs.World) {
+		entities = make([]Entity, 0, len(w.Entities))
+		for e := range w.Entities {
+			entities = append(entities, Entity{ID: e, Name: w.Name(e)})
+		}
+	})
+	slices.SortFunc(entities, func(a, b Entity) int { return cmp.Compare(a.ID, b.ID) })
+	writeJSON(rw, http.StatusOK, entities)
+}
+
+func (h handler) entity(rw http.ResponseWriter, r *http.Request) {
+	var (
+		body []byte
+		code int
+		err  error
+	)
+	// The values may share memory with the World, so they are encoded before the lock is released.
+	h.sync.Read(func(w *ecs.World) {
+		var id ecs.Entity
+		if id, err = lookup(w, r.PathValue("id")); err != nil {
+			code = http.StatusNotFound
+			return
+		}
+		code = http.StatusInternalServerError
+		body, err = encode(describe(w, id))
+	})
+	if err != nil {
+		writeError(rw, code, err)
+		return
+	}
+	writeBody(rw, http.StatusOK, body)
+}
+
+func (h handler) setComp(rw http.ResponseWriter, r *http.Request) {
+	var (
+		e    ecs.Entity
+		c    ecs.Component
+		typ  reflect.Type
+		code int
+		err  error
+	)
+	h.sync.Read(func(w *ecs.World) {
+		if code, e, c, err = lookupComp(w, r); err != nil {
+			return
+		}
+		typ = w.CompType(c)
+		if typ != nil && isTag(w, e, c) {
+			code, err = http.StatusBadRequest, fmt.Errorf("component %q is a tag of the entity", r.PathValue("comp"))
+		}
+	})
+	if err != nil {
+		writeError(rw, code, err)
+		return
+	}
+
+	st := h.sync.Stage()
+	if typ == nil {
+		st.Do(func(w *ecs.World) {
+			if _, ok := w.Entities[e]; ok {
+				w.AddComp(e, c)
+			}
+		})
+	} else {
+		v := reflect.New(typ)
+		if err := json.NewDecoder(r.Body).Decode(v.Interface()); err != nil {
+			writeError(rw, http.StatusBadRequest, err)
+			return
+		}
+		st.Do(func(w *ecs.World) {
+			// The Component may have been added as a tag since the request.
+			if _, ok := w.Entities[e]; ok && !isTag(w, e, c) {
+				w.SetCompValue(e, c, v.Elem().Interface())
+			}
+		})
+	}
+	st.Commit()
+	rw.WriteHeader(http.StatusAccepted)
+}
+
+func (h handler) delComp(rw http.ResponseWriter, r *http.Request) {
+	var (
+		e    ecs.Entity
+		c    ecs.Component
+		code int
+		err  error
+	)
+	h.sync.Read(func(w *ecs.World) { code, e, c, err = lookupComp(w, r) })
+	if err != nil {
+		writeError(rw, code, err)
+		return
+	}
+	st := h.sync.Stage()
+	st.Do(func(w *ecs.World) {
+		if _, ok := w.Entities[e]; ok {
+			w.DelComp(e, c)
+		}
+	})
+	st.Commit()
+	rw.WriteHeader(http.StatusAccepted)
+}
+
+func (h handler) query(rw http.ResponseWriter, r *http.Request) {
+	var (
+		body []byte
+		code int
+		err  error
+	)
+	// Parsing doesn't modify the World, so it's safe under the read lock.
+	h.sync.Read(func(w *ecs.World) {
+		var f ecs.Filter
+		if f, err = w.ParseQuery(r.FormValue("q")); err != nil {
+			code = http.StatusBadRequest
+			return
+		}
+		var entities []Entity
+		for e := range w.Iter(f) {
+			entities = append(entities, describe(w, e))
+		}
+		slices.SortFunc(entities, func(a, b Entity) int { return cmp.Compare(a.ID, b.ID) })
+		code = http.StatusInternalServerError
+		body, err = encode(entities)
+	})
+	if err != nil {
+		writeError(rw, code, err)
+		return
+	}
+	writeBody(rw, http.StatusOK, body)
+}
+
+func (h handler) archetypes(rw http.ResponseWriter, r *http.Request) {
+	var archetypes []Archetype
+	h.sync.Read(func(w *ecs.World) {
+		for _, t := range w.Stats().Tables {
+			a := Archetype{Components: make([]Component, len(t.Types)), Entities: t.Rows}
+			for i, c := range t.Types {
+				a.Components[i] = Component{ID: c, Name: w.Name(ecs.Entity(c))}
+			}
+			archetypes = append(archetypes, a)
+		}
+	})
+	writeJSON(rw, http.StatusOK, archetypes)
+}
+
+func (h handler) systems(rw http.ResponseWriter, r *http.Request) {
+	var systems []System
+	h.sync.Read(func(w *ecs.World) {
+		for _, s := range w.Systems() {
+			systems = append(systems, System{Name: s.Name, Entities: s.Query.Count()})
+		}
+	})
+	writeJSON(rw, http.StatusOK, systems)
+}
+
+// describe returns the JSON form of the Entity, with its Components.
+// The values are shallow copies of the data, so they must be encoded holding the lock.
+func describe(w *ecs.World, e ecs.Entity) Entity {
+	out := Entity{ID: e, Name: w.Name(e)}
+	rec := w.Entities[e]
+	out.Components = make([]Component, len(rec.AT.Types))
+	for i, t := range rec.AT.Types {
+		out.Components[i] = Component{ID: t.Component, Name: w.Name(ecs.Entity(t.Component))}
+		if s := rec.AT.Comps[i]; s != nil {
+			out.Components[i].Value = s.Get(rec.Row)
+		}
+	}
+	return out
+}
+
+// lookup finds the alive Entity by its id or name.
+func lookup(w *ecs.World, s string) (ecs.Entity, error) {
+	e, ok := w.Lookup(s)
+	if !ok {
+		id, err := strconv.ParseUint(s, 10, 64)
+		e = ecs.Entity(id)
+		ok = err == nil
+	}
+	if _, alive := w.Entities[e]; !ok || !alive {
+		return 0, fmt.Errorf("entity not found: %q", s)
+	}
+	return e, nil
+}
+
+// isTag reports whether the Entity has the Component as a tag, while it may be data of the other entities.
+func isTag(w *ecs.World, e ecs.Entity, c ecs.Component) bool {
+	col, ok := w.Components[c][w.Entities[e].AT]
+	return ok && col == -1
+}
+
+// lookupComp finds the Entity and the Component in the path of the request.
+// If they are not found, the status code and the error are returned.
+func lookupComp(w *ecs.World, r *http.Request) (code int, e ecs.Entity, c ecs.Component, err error) {
+	if e, err = lookup(w, r.PathValue("id")); err != nil {
+		return http.StatusNotFound, 0, 0, err
+	}
+	var ce ecs.Entity
+	if ce, err = lookup(w, r.PathValue("comp")); err != nil {
+		return http.StatusNotFound, 0, 0, err
+	}
+	c = ecs.Component(ce)
+	if _, ok := w.Components[c]; !ok {
+		return http.StatusBadRequest, 0, 0, fmt.Errorf("not a component: %q", r.PathValue("comp"))
+	}
+	return http.StatusOK, e, c, nil
+}
+
+func writeJSON(rw http.ResponseWriter, code int, v any) {
+	body, err := encode(v)
+	if err != nil {
+		writeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+	writeBody(rw, code, body)
+}
+
+// encode returns the JSON encoding of v, ending with a newline.
+func encode(v any) ([]byte, error) {
+	body, err := json.Marshal(v)
+	return append(body, '\n'), err
+}
+
+func writeBody(rw http.ResponseWriter, code int, body []byte) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	rw.Write(body)
+}
+
+func writeError(rw http.ResponseWriter, code int, err error) {
+	writeJSON(rw, code, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,191 @@
+package ecshttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Tnze/go-ecs"
+)
+
+type Position struct{ X, Y float64 }
+
+func TestHandler(t *testing.T) {
+	w := ecs.NewWorld()
+	position := w.NewComponent()
+	w.SetName(ecs.Entity(position), "Position")
+	player := w.NewComponent()
+	w.SetName(ecs.Entity(player), "Player")
+	e := w.NewEntity()
+	w.SetName(e, "hero")
+	w.SetComp(e, position, Position{1, 2})
+	w.AddComp(e, player)
+	w.AddSystem("Move", ecs.QueryAll(position), func(entities []ecs.Entity, data []any) {})
+
+	s := ecs.NewSyncWorld(w)
+	srv := httptest.NewServer(Handler(s))
+	defer srv.Close()
+
+	do := func(t *testing.T, method, path, body string, wantCode int, out any) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != wantCode {
+			t.Fatalf("%s %s get: %s, want: %d", method, path, resp.Status, wantCode)
+		}
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var entities []Entity
+	do(t, "GET", "/entities", "", http.StatusOK, &entities)
+	if len(entities) != 3 || entities[2].Name != "hero" {
+		t.Errorf("entities get: %+v", entities)
+	}
+
+	var hero Entity
+	do(t, "GET", "/entities/hero", "", http.StatusOK, &hero)
+	if len(hero.Components) != 2 || hero.Components[0].Name != "Position" || hero.Components[1].Value != nil {
+		t.Errorf("entity get: %+v", hero)
+	}
+	if v := hero.Components[0].Value.(map[string]any); v["X"] != 1.0 || v["Y"] != 2.0 {
+		t.Errorf("the value of Position get: %v", v)
+	}
+	do(t, "GET", "/entities/100", "", http.StatusNotFound, nil)
+
+	// The changes are applied at the sync point.
+	do(t, "PUT", "/entities/hero/components/Position", `{"X": 3, "Y": 4}`, http.StatusAccepted, nil)
+	do(t, "DELETE", "/entities/hero/components/Player", "", http.StatusAccepted, nil)
+	do(t, "PUT", "/entities/hero/components/Position", `"bad"`, http.StatusBadRequest, nil)
+	do(t, "PUT", "/entities/hero/components/hero", "", http.StatusBadRequest, nil)
+	if got := *w.GetComp[Position](e, position); got != (Position{1, 2}) {
+		t.Errorf("the change is applied before the sync point")
+	}
+	s.Sync()
+	if got := *w.GetComp[Position](e, position); got != (Position{3, 4}) || w.HasComp(e, player) {
+		t.Errorf("the changes are not applied: %v", got)
+	}
+
+	var result []Entity
+	do(t, "GET", "/query?q=Position,!Player", "", http.StatusOK, &result)
+	if len(result) != 1 || result[0].ID != e {
+		t.Errorf("query get: %+v", result)
+	}
+	do(t, "GET", "/query?q=Position,,", "", http.StatusBadRequest, nil)
+
+	var archetypes []Archetype
+	do(t, "GET", "/archetypes", "", http.StatusOK, &archetypes)
+	if len(archetypes) == 0 || archetypes[0].Entities != 2 {
+		t.Errorf("archetypes get: %+v", archetypes)
+	}
+
+	var systems []System
+	do(t, "GET", "/systems", "", http.StatusOK, &systems)
+	if len(systems) != 1 || systems[0].Name != "Move" || systems[0].Entities != 1 {
+		t.Errorf("systems get: %+v", systems)
+	}
+
+	// The Entity is deleted before the sync point.
+	do(t, "PUT", "/entities/hero/components/Player", "", http.StatusAccepted, nil)
+	w.DelEntity(e)
+	s.Sync()
+}
+
+func TestHandler_concurrentQuery(t *testing.T) {
+	w := ecs.NewWorld()
+	childOf := w.NewComponent()
+	w.SetName(ecs.Entity(childOf), "ChildOf")
+	position := w.NewComponent()
+	w.SetName(ecs.Entity(position), "Position")
+	scene := w.NewEntity()
+	w.SetName(scene, "Scene")
+	w.SetComp(w.NewEntity(), position, Position{})
+
+	// The Pair (ChildOf, Scene) doesn't exist, so the queries must not create it.
+	s := ecs.NewSyncWorld(w)
+	h := Handler(s)
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, httptest.NewRequest("GET", "/query?q=Position,(ChildOf,Scene)", nil))
+			if rw.Code != http.StatusOK {
+				t.Errorf("query get: %d %s", rw.Code, rw.Body)
+			}
+		})
+	}
+	wg.Wait()
+	if _, ok := w.Pairs[ecs.Pair{Rel: childOf, Target: scene}]; ok {
+		t.Error("the query creates the Pair")
+	}
+}
+
+func TestHandler_tag(t *testing.T) {
+	w := ecs.NewWorld()
+	score, player := w.NewComponent(), w.NewComponent()
+	w.SetName(ecs.Entity(score), "Score")
+	e := w.NewEntity()
+	w.AddComp(e, player)
+	w.AddComp(e, score)
+	w.SetComp(w.NewEntity(), score, 1)
+
+	s := ecs.NewSyncWorld(w)
+	rw := httptest.NewRecorder()
+	Handler(s).ServeHTTP(rw, httptest.NewRequest("PUT", "/entities/"+strconv.Itoa(int(e))+"/components/Score", strings.NewReader("2")))
+	if rw.Code != http.StatusBadRequest {
+		t.Errorf("PUT to a tag get: %d %s", rw.Code, rw.Body)
+	}
+	s.Sync()
+}
+
+func TestHandler_concurrentWrite(t *testing.T) {
+	w := ecs.NewWorld()
+	history := w.NewComponent()
+	w.SetName(ecs.Entity(history), "History")
+	e := w.NewEntity()
+	w.SetName(e, "hero")
+	w.SetComp(e, history, []int{1, 2, 3})
+
+	// The values share the backing array with the World, while it's written at the sync points.
+	s := ecs.NewSyncWorld(w)
+	h := Handler(s)
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 20 {
+				rw := httptest.NewRecorder()
+				h.ServeHTTP(rw, httptest.NewRequest("GET", "/entities/hero", nil))
+				if rw.Code != http.StatusOK {
+					t.Errorf("entity get: %d %s", rw.Code, rw.Body)
+				}
+			}
+		})
+	}
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	for i := 0; ; i++ {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		st := s.Stage()
+		st.Do(func(w *ecs.World) { (*w.GetComp[[]int](e, history))[0] = i })
+		st.Commit()
+		s.Sync()
+	}
+}
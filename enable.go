@@ -0,0 +1,161 @@
+package ecs
+
+import (
+	"math/bits"
+	"slices"
+	"sort"
+)
+
+// Disable hides the Entity from queries, without removing its Components.
+// Unlike removing a Component, the Entity stays in its archetype,
+// so disabling and enabling a large number of entities is cheap.
+//
+// Disabled entities are visible to the Filters wrapped by WithDisabled.
+func (w *World) Disable(e Entity) {
+	if w.journal != nil {
+		w.journal.toggling("Disable", e)
+	}
+	rec := w.Entities[e]
+	w.touch(rec.AT)
+	rec.AT.disabled.set(rec.Row)
+	rec.AT.version++
+}
+
+// Enable makes a disabled Entity visible to queries again.
+// If the Entity isn't disabled, nothing will happen.
+func (w *World) Enable(e Entity) {
+	if w.journal != nil {
+		w.journal.toggling("Enable", e)
+	}
+	rec := w.Entities[e]
+	w.touch(rec.AT)
+	rec.AT.disabled.unset(rec.Row)
+	rec.AT.version++
+}
+
+// IsEnabled reports whether the Entity is visible to queries.
+func (w *World) IsEnabled(e Entity) bool {
+	rec := w.Entities[e]
+	return !rec.AT.disabled.has(rec.Row)
+}
+
+// WithDisabled returns a Filter which matches the same archetypes as f,
+// but also lets the queries see the disabled entities.
+func WithDisabled(f Filter) Filter {
+	return func(w *World, a *Archetype, m *Match) bool {
+		m.Disabled = true
+		return f(w, a, m)
+	}
+}
+
+// DisableComp switches off the Component of the Entity, without removing it or its data.
+// The Entity is hidden from the queries requiring all of the disabled Component,
+// while the data stays in place and is kept when the Component is enabled again.
+// This is much cheaper than DelComp and SetComp, which move the Entity to another archetype.
+//
+// If the Entity doesn't have the Component, nothing will happen.
+func (w *World) DisableComp(e Entity, c Component) {
+	rec := w.Entities[e]
+	if i := rec.AT.index(c); i != -1 {
+		if w.journal != nil {
+			w.journal.changing("DisableComp", e, c)
+		}
+		w.touch(rec.AT)
+		rec.AT.toggles[i].set(rec.Row)
+		rec.AT.version++
+	}
+}
+
+// EnableComp switches on the Component of the Entity, which is disabled by DisableComp.
+// If the Entity doesn't have the Component, nothing will happen.
+func (w *World) EnableComp(e Entity, c Component) {
+	rec := w.Entities[e]
+	if i := rec.AT.index(c); i != -1 {
+		if w.journal != nil {
+			w.journal.changing("EnableComp", e, c)
+		}
+		w.touch(rec.AT)
+		rec.AT.toggles[i].unset(rec.Row)
+		rec.AT.version++
+	}
+}
+
+// IsCompEnabled reports whether the Entity has the Component and it's not disabled.
+func (w *World) IsCompEnabled(e Entity, c Component) bool {
+	rec := w.Entities[e]
+	i := rec.AT.index(c)
+	return i != -1 && !rec.AT.toggles[i].has(rec.Row)
+}
+
+// index returns the index of the Component in the archetype's Types, or -1 if it's not found.
+// Unlike the columns in World.Components, this also works for tags.
+func (a *Archetype) index(c Component) int {
+	i := sort.Search(len(a.Types), func(i int) bool { return a.Types[i].Component >= c })
+	if i < len(a.Types) && a.Types[i].Component == c {
+		return i
+	}
+	return -1
+}
+
+// bitset is a set of rows in an archetype.
+// Rows not stored in the words are considered unset,
+// so appending rows to the archetype doesn't require growing the bitset.
+type bitset struct {
+	words []uint64
+	count int // The number of set bits, allows skipping the check of every row.
+}
+
+func (b *bitset) has(i int) bool {
+	w := i / 64
+	return w < len(b.words) && b.words[w]&(1<<(i%64)) != 0
+}
+
+func (b *bitset) set(i int) {
+	w := i / 64
+	if w >= len(b.words) {
+		b.words = append(b.words, make([]uint64, w+1-len(b.words))...)
+	}
+	if b.words[w]&(1<<(i%64)) == 0 {
+		b.words[w] |= 1 << (i % 64)
+		b.count++
+	}
+}
+
+func (b *bitset) unset(i int) {
+	w := i / 64
+	if w < len(b.words) && b.words[w]&(1<<(i%64)) != 0 {
+		b.words[w] &^= 1 << (i % 64)
+		b.count--
+	}
+}
+
+// clone returns a copy of the bits of the rows [0, n).
+func (b *bitset) clone(n int) (c bitset) {
+	words := (n + 63) / 64
+	c.words = slices.Clone(b.words[:min(words, len(b.words))])
+	if len(c.words) == words && n%64 != 0 {
+		c.words[words-1] &= 1<<(n%64) - 1
+	}
+	for _, w := range c.words {
+		c.count += bits.OnesCount64(w)
+	}
+	return
+}
+
+// swapDelete does the same thing to the bits as Table.swapDelete does to the rows,
+// where last is the index of the last row.
+func (b *bitset) swapDelete(i, last int) {
+	if b.count == 0 {
+		return
+	}
+	moved := b.has(last)
+	b.unset(last)
+	if i == last {
+		return
+	}
+	if moved {
+		b.set(i)
+	} else {
+		b.unset(i)
+	}
+}
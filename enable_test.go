@@ -0,0 +1,175 @@
+package ecs
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWorld_Disable(t *testing.T) {
+	w := NewWorld()
+	c1 := w.NewComponent()
+	c2 := w.NewComponent()
+	c3 := w.NewComponent()
+
+	var entities [10]Entity
+	for i := range entities {
+		entities[i] = w.NewEntity()
+		w.SetComp(entities[i], c1, i)
+	}
+	query := w.Cache(QueryAll(c1))
+
+	w.Disable(entities[2])
+	w.Disable(entities[5])
+	w.Disable(entities[6])
+
+	// id: [0 1 2 3 4 5 6 7 8 9]
+	// c1: [0 1 _ 3 4 _ _ 7 8 9]
+
+	judge := func(t *testing.T, f Filter, want []int) {
+		t.Helper()
+		var result []int
+		w.Query(f, func(entities []Entity, data []any) {
+			if len(entities) != len(*data[0].(*[]int)) {
+				t.Errorf("len(entities) = %d, len(data) = %d", len(entities), len(*data[0].(*[]int)))
+			}
+			result = append(result, *data[0].(*[]int)...)
+		})
+		sort.Ints(result)
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("Query get: %v, want: %v", result, want)
+		}
+
+		result = result[:0]
+		for _, data := range w.Iter(f) {
+			result = append(result, data[0].(int))
+		}
+		sort.Ints(result)
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("Iter get: %v, want: %v", result, want)
+		}
+	}
+	judgeCached := func(t *testing.T, want []int) {
+		t.Helper()
+		var result []int
+		query.Run(func(entities []Entity, data []any) {
+			result = append(result, *data[0].(*[]int)...)
+		})
+		sort.Ints(result)
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("CachedQuery.Run get: %v, want: %v", result, want)
+		}
+
+		result = result[:0]
+		for _, data := range query.Iter {
+			result = append(result, data[0].(int))
+		}
+		sort.Ints(result)
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("CachedQuery.Iter get: %v, want: %v", result, want)
+		}
+	}
+
+	judge(t, QueryAll(c1), []int{0, 1, 3, 4, 7, 8, 9})
+	judge(t, WithDisabled(QueryAll(c1)), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	judgeCached(t, []int{0, 1, 3, 4, 7, 8, 9})
+
+	// Moving entities between archetypes keeps them disabled,
+	// and the entities moved into the deleted rows keep their state.
+	w.SetComp(entities[5], c2, 5)
+	w.AddComp(entities[0], c3)
+	w.DelEntity(entities[3])
+
+	// id: [0 1 2 3 4 5 6 7 8 9]
+	// c1: [0 1 _ x 4 _ _ 7 8 9]
+	// c2: [          _        ]
+	// c3: [0                  ]
+
+	if w.IsEnabled(entities[5]) || w.IsEnabled(entities[6]) || !w.IsEnabled(entities[9]) {
+		t.Error("enabled state isn't kept after moving entities")
+	}
+	judge(t, QueryAll(c1), []int{0, 1, 4, 7, 8, 9})
+	judge(t, QueryAll(c1, c2), nil)
+	judge(t, WithDisabled(QueryAll(c1, c2)), []int{5})
+	judge(t, QueryAll(c1, c3), []int{0})
+	judgeCached(t, []int{0, 1, 4, 7, 8, 9})
+
+	for _, e := range entities {
+		if e != entities[3] {
+			w.Enable(e)
+		}
+	}
+	judgeCached(t, []int{0, 1, 2, 4, 5, 6, 7, 8, 9})
+}
+
+func BenchmarkWorld_Disable(b *testing.B) {
+	w := NewWorld()
+	c := w.NewComponent()
+	entities := make([]Entity, 100_000)
+	for i := range entities {
+		entities[i] = w.NewEntity()
+		w.SetComp(entities[i], c, i)
+	}
+
+	for b.Loop() {
+		for _, e := range entities {
+			w.Disable(e)
+		}
+		for _, e := range entities {
+			w.Enable(e)
+		}
+	}
+}
+
+func TestWorld_DisableComp(t *testing.T) {
+	w := NewWorld()
+	health := w.NewComponent()
+	poisoned := w.NewComponent()
+	frozen := w.NewComponent()
+
+	var entities [6]Entity
+	for i := range entities {
+		entities[i] = w.NewEntity()
+		w.SetComp(entities[i], health, i)
+		w.AddComp(entities[i], poisoned)
+	}
+	poisonQuery := w.Cache(QueryAll(health, poisoned))
+
+	w.DisableComp(entities[1], poisoned)
+	w.DisableComp(entities[4], health)
+	w.DisableComp(entities[5], frozen) // doesn't have the component
+
+	// id:       [0 1 2 3 4 5]
+	// health:   [0 1 2 3 _ 5]
+	// poisoned: [x _ x x x x]
+
+	judge := func(t *testing.T, q *CachedQuery, want []int) {
+		t.Helper()
+		var result []int
+		q.Run(func(entities []Entity, data []any) {
+			result = append(result, *data[0].(*[]int)...)
+		})
+		sort.Ints(result)
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("get: %v, want: %v", result, want)
+		}
+	}
+	judge(t, poisonQuery, []int{0, 2, 3, 5})
+	judge(t, w.Cache(QueryAll(health)), []int{0, 1, 2, 3, 5})
+	judge(t, w.Cache(QueryAny(health)), []int{0, 1, 2, 3, 4, 5})
+
+	// The toggles and the data are kept when moving entities.
+	w.AddComp(entities[1], frozen)
+	w.AddComp(entities[4], frozen)
+	if w.IsCompEnabled(entities[1], poisoned) || w.IsCompEnabled(entities[4], health) {
+		t.Error("disabled components are enabled after moving entities")
+	}
+	if !w.IsCompEnabled(entities[1], health) || w.IsCompEnabled(entities[1], 100) {
+		t.Error("IsCompEnabled reports wrong result")
+	}
+	judge(t, poisonQuery, []int{0, 2, 3, 5})
+
+	w.EnableComp(entities[1], poisoned)
+	w.EnableComp(entities[4], health)
+	judge(t, poisonQuery, []int{0, 1, 2, 3, 4, 5})
+}
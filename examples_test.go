@@ -301,3 +301,35 @@ func ExampleQueryAny_cache_iter() {
 	// e5: [c1: <nil> c2: 5]
 	// e6: [c1: <nil> c2: 6]
 }
+
+func ExampleFilter() {
+	w := ecs.NewWorld()
+	c1 := w.NewComponent()
+	c2 := w.NewComponent()
+	for i := range 4 {
+		e := w.NewEntity()
+		w.SetComp(e, c1, i)
+		if i%2 == 0 {
+			w.SetComp(e, c2, i)
+		}
+	}
+
+	// A custom Filter matching the archetypes having c1 but not c2.
+	// The Filters used to take a *[]int and append the columns to it,
+	// now the columns are appended to Match.Columns instead.
+	without := func(w *ecs.World, a *ecs.Archetype, out *ecs.Match) bool {
+		col, ok := w.Components[c1][a]
+		if _, has := w.Components[c2][a]; !ok || has {
+			return false
+		}
+		out.Columns = append(out.Columns, col) // It was `*out = append(*out, col)`.
+		return true
+	}
+
+	w.Query(without, func(entities []ecs.Entity, data []any) {
+		fmt.Println(*data[0].(*[]int))
+	})
+
+	// Output:
+	// [1 3]
+}
@@ -0,0 +1,57 @@
+package ecs
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// WriteArchetypeGraph writes the graph of the archetypes in Graphviz DOT format.
+// Each archetype is a node labelled by the names of its Components and the number of its entities,
+// and each cached ArchetypeEdge is an arrow labelled by the Component added (+) or deleted (-).
+//
+// The Components without names are labelled by their ids.
+// The graph can be rendered by: dot -Tsvg graph.dot -o graph.svg
+func (w *World) WriteArchetypeGraph(out io.Writer) error {
+	// Number the archetypes in a stable order.
+	archetypes := make([]*Archetype, 0, len(w.Archetypes))
+	for _, a := range w.Archetypes {
+		archetypes = append(archetypes, a)
+	}
+	slices.SortFunc(archetypes, func(a, b *Archetype) int {
+		return slices.CompareFunc(a.Types, b.Types, func(a, b ComponentMeta) int {
+			return cmp.Compare(a.Component, b.Component)
+		})
+	})
+	ids := make(map[*Archetype]int, len(archetypes))
+	for i, a := range archetypes {
+		ids[a] = i
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("digraph archetypes {\n\tnode [shape=box];\n")
+	for i, a := range archetypes {
+		fmt.Fprintf(&buf, "\ta%d [label=%q];\n", i, fmt.Sprintf("%s\n%d entities", w.typesLabel(a.Types), len(a.entities)))
+	}
+	for i, a := range archetypes {
+		comps := make([]Component, 0, len(a.edges))
+		for c := range a.edges {
+			comps = append(comps, c)
+		}
+		slices.Sort(comps)
+		for _, c := range comps {
+			edge := a.edges[c]
+			if edge.add != nil {
+				fmt.Fprintf(&buf, "\ta%d -> a%d [label=%q];\n", i, ids[edge.add], "+"+w.label(Entity(c)))
+			}
+			if edge.del != nil {
+				fmt.Fprintf(&buf, "\ta%d -> a%d [label=%q, style=dashed];\n", i, ids[edge.del], "-"+w.label(Entity(c)))
+			}
+		}
+	}
+	buf.WriteString("}\n")
+	_, err := buf.WriteTo(out)
+	return err
+}
@@ -0,0 +1,39 @@
+package ecs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWorld_WriteArchetypeGraph(t *testing.T) {
+	w := NewWorld()
+	position := w.NewComponent()
+	w.SetName(Entity(position), "Position")
+	childOf := w.NewComponent()
+	w.SetName(Entity(childOf), "ChildOf")
+
+	parent := w.NewEntity()
+	e := w.NewEntity()
+	w.SetComp(e, position, struct{}{})
+	w.AddComp(e, w.Pair(childOf, parent))
+	w.DelComp(e, position)
+
+	var sb strings.Builder
+	if err := w.WriteArchetypeGraph(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph archetypes {
+	node [shape=box];
+	a0 [label="[]\n4 entities"];
+	a1 [label="[Position]\n0 entities"];
+	a2 [label="[Position, (ChildOf, #2)]\n0 entities"];
+	a3 [label="[(ChildOf, #2)]\n1 entities"];
+	a0 -> a1 [label="+Position"];
+	a1 -> a2 [label="+(ChildOf, #2)"];
+	a2 -> a3 [label="-Position", style=dashed];
+}
+`
+	if got := sb.String(); got != want {
+		t.Errorf("get:\n%s\nwant:\n%s", got, want)
+	}
+}
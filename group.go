@@ -0,0 +1,41 @@
+package ecs
+
+// GroupBy makes the CachedQuery group its matched archetypes by the key,
+// so that the entities of a group can be iterated by CachedQuery.RunGroup without scanning other archetypes.
+//
+// The key function is called once for each matched archetype.
+// If ok is false, the archetype doesn't belong to any group, but is still iterated by CachedQuery.Run.
+func GroupBy(key func(w *World, t Types) (key uint64, ok bool)) CacheOption {
+	return func(q *CachedQuery) {
+		q.groupBy = key
+		q.groups = make(map[uint64][]*Archetype)
+	}
+}
+
+// GroupByRel makes the CachedQuery group its matched archetypes by the target of their relationship of kind rel.
+// The key of a group is the target Entity. The archetypes without the relationship don't belong to any group.
+//
+// For example, with GroupByRel(ChildOf), the children of a scene can be iterated by RunGroup(uint64(scene), h).
+func GroupByRel(rel Component) CacheOption {
+	return GroupBy(func(w *World, t Types) (uint64, bool) {
+		for _, c := range t {
+			if p, ok := w.pairOf[c.Component]; ok && p.Rel == rel {
+				return uint64(p.Target), true
+			}
+		}
+		return 0, false
+	})
+}
+
+// RunGroup is like Run, but only iterates the archetypes in the group of the key.
+// The CachedQuery must be created with the GroupBy or GroupByRel option.
+func (q *CachedQuery) RunGroup(key uint64, h func(entities []Entity, data []any)) {
+	data := q.data[:0]
+	for _, a := range q.groups[key] {
+		if len(a.entities) > 0 {
+			data = q.matches[q.index[a]].run(q.world, a, data, h)
+		}
+	}
+	clear(data)
+	q.data = data
+}
@@ -0,0 +1,51 @@
+package ecs
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCachedQuery_RunGroup(t *testing.T) {
+	w := NewWorld()
+	position := w.NewComponent()
+	childOf := w.NewComponent()
+	sceneA := w.NewEntity()
+	sceneB := w.NewEntity()
+
+	q := w.Cache(QueryAll(position), GroupByRel(childOf))
+	for i := range 6 {
+		e := w.NewEntity()
+		w.SetComp(e, position, i)
+		switch i % 3 {
+		case 0:
+			w.AddComp(e, w.Pair(childOf, sceneA))
+		case 1:
+			w.AddComp(e, w.Pair(childOf, sceneB))
+		}
+	}
+
+	judge := func(t *testing.T, key uint64, want []int) {
+		t.Helper()
+		var result []int
+		q.RunGroup(key, func(entities []Entity, data []any) {
+			result = append(result, *data[0].(*[]int)...)
+		})
+		sort.Ints(result)
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("group %d get: %v, want: %v", key, result, want)
+		}
+	}
+	judge(t, uint64(sceneA), []int{0, 3})
+	judge(t, uint64(sceneB), []int{1, 4})
+	judge(t, 12345, nil)
+
+	// Group by a custom key
+	tag := w.NewComponent()
+	w.AddComp(w.NewEntity(), tag)
+	q = w.Cache(QueryAll(position), GroupBy(func(w *World, t Types) (uint64, bool) {
+		return uint64(len(t)), true
+	}))
+	judge(t, 1, []int{2, 5})
+	judge(t, 2, []int{0, 1, 3, 4})
+}
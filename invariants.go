@@ -0,0 +1,141 @@
+package ecs
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// CheckInvariants verifies the internal consistency of the World, and returns the violations found joined by errors.Join.
+// It returns nil if the World is consistent.
+//
+// It checks that:
+//   - the record of every Entity points to the row of the Entity in its archetype,
+//   - every column of an archetype has the same length as its entities,
+//   - the Components map agrees with the Types of the archetypes,
+//   - every ArchetypeEdge leads to an archetype which differs by exactly the Component of the edge,
+//   - every cached query matches exactly the archetypes accepted by its Filter.
+//
+// It's slow, and is meant to be used in tests and debug builds.
+func (w *World) CheckInvariants() error {
+	var errs []error
+	report := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("ecs: "+format, args...))
+	}
+
+	for e, rec := range w.Entities {
+		switch {
+		case rec.AT == nil || rec.Row < 0 || rec.Row >= len(rec.AT.entities):
+			report("entity %d: the record is out of its archetype", e)
+		case rec.AT.entities[rec.Row] != e:
+			report("entity %d: the row %d belongs to entity %d", e, rec.Row, rec.AT.entities[rec.Row])
+		case rec.AT.records[rec.Row] != rec:
+			report("entity %d: the row %d doesn't point back to its record", e, rec.Row)
+		}
+	}
+
+	for hash, a := range w.Archetypes {
+		name := w.typesLabel(a.Types)
+		// The Types are cloned to not be sorted in place.
+		if !slices.IsSortedFunc(a.Types, func(a, b ComponentMeta) int { return cmp.Compare(a.Component, b.Component) }) {
+			report("archetype %s: the Types are not sorted", name)
+		} else if slices.Clone(a.Types).sortHash(&w.hash) != hash {
+			report("archetype %s: stored with a wrong hash", name)
+		}
+		if len(a.records) != len(a.entities) {
+			report("archetype %s: %d records for %d entities", name, len(a.records), len(a.entities))
+		}
+		for row, e := range a.entities {
+			if rec, ok := w.Entities[e]; !ok || rec.AT != a || rec.Row != row {
+				report("archetype %s: the entity %d at row %d isn't recorded there", name, e, row)
+			}
+		}
+		if len(a.Comps) != len(a.Types) || len(a.toggles) != len(a.Types) || len(a.changed) != len(a.Types) {
+			report("archetype %s: the columns don't match the Types", name)
+			continue
+		}
+		for i, t := range a.Types {
+			col, ok := w.Components[t.Component][a]
+			switch s := a.Comps[i]; {
+			case !ok:
+				report("archetype %s: Component %s isn't indexed in Components", name, w.label(Entity(t.Component)))
+			case (s == nil) != (t.TableType == nil):
+				report("archetype %s: the column of %s doesn't match its TableType", name, w.label(Entity(t.Component)))
+			case s == nil && col != -1 || s != nil && col != i:
+				report("archetype %s: Components has the column %d for %s at %d", name, col, w.label(Entity(t.Component)), i)
+			case s != nil && reflect.ValueOf(s).Elem().Len() != len(a.entities):
+				report("archetype %s: the column of %s has %d rows for %d entities",
+					name, w.label(Entity(t.Component)), reflect.ValueOf(s).Elem().Len(), len(a.entities))
+			}
+		}
+
+		for c, edge := range a.edges {
+			if edge.add != nil && !w.differByOne(a, edge.add, c) {
+				report("archetype %s: the edge adding %s leads to %s", name, w.label(Entity(c)), w.typesLabel(edge.add.Types))
+			}
+			if edge.del != nil && !w.differByOne(edge.del, a, c) {
+				report("archetype %s: the edge deleting %s leads to %s", name, w.label(Entity(c)), w.typesLabel(edge.del.Types))
+			}
+		}
+	}
+
+	for c, archetypes := range w.Components {
+		for a := range archetypes {
+			if w.Archetypes[slices.Clone(a.Types).sortHash(&w.hash)] != a || a.index(c) == -1 {
+				report("Component %s: indexed in an archetype without it", w.label(Entity(c)))
+			}
+		}
+	}
+
+	for _, q := range w.CachedQueries() {
+		errs = append(errs, w.checkQuery(q)...)
+	}
+	return errors.Join(errs...)
+}
+
+// differByOne reports whether archetype b has exactly the Components of archetype a plus c.
+func (w *World) differByOne(a, b *Archetype, c Component) bool {
+	if len(b.Types) != len(a.Types)+1 || a.index(c) != -1 || b.index(c) == -1 {
+		return false
+	}
+	for _, t := range a.Types {
+		if b.index(t.Component) == -1 {
+			return false
+		}
+	}
+	return true
+}
+
+// checkQuery verifies that the tables of the CachedQuery are exactly the archetypes matched by its Filter.
+func (w *World) checkQuery(q *CachedQuery) (errs []error) {
+	report := func(a *Archetype, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("ecs: cached query %p: archetype %s: "+format, append([]any{q, w.typesLabel(a.Types)}, args...)...))
+	}
+	for _, a := range w.Archetypes {
+		var m Match
+		matched := q.filter(w, a, &m)
+		i, cached := q.index[a]
+		switch {
+		case matched && !cached:
+			report(a, "matched but not cached")
+		case !matched && cached:
+			report(a, "cached but not matched")
+		case !cached:
+		case i >= len(q.tables) || q.tables[i] != a:
+			report(a, "the index is wrong")
+		case (i < q.active) != (len(a.entities) > 0):
+			report(a, "in the wrong list of active and inactive tables")
+		case !slices.Equal(m.Columns, q.matches[i].Columns) ||
+			!slices.Equal(m.Toggles, q.matches[i].Toggles) ||
+			!slices.Equal(m.Sources, q.matches[i].Sources) ||
+			m.Disabled != q.matches[i].Disabled:
+			report(a, "the cached Match is outdated")
+		}
+	}
+	if len(q.index) != len(q.tables) {
+		errs = append(errs, fmt.Errorf("ecs: cached query %p: %d tables but %d indexed", q, len(q.tables), len(q.index)))
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package ecs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWorld_CheckInvariants(t *testing.T) {
+	w := NewWorld()
+	position := w.NewComponent()
+	tag := w.NewComponent()
+	childOf := w.NewComponent()
+	q := w.Cache(QueryAll(position))
+	defer q.Close()
+
+	parent := w.NewEntity()
+	var entities []Entity
+	for i := range 20 {
+		e := w.NewEntity()
+		w.SetComp(e, position, i)
+		if i%2 == 0 {
+			w.AddComp(e, tag)
+		}
+		if i%3 == 0 {
+			w.AddComp(e, w.Pair(childOf, parent))
+		}
+		entities = append(entities, e)
+	}
+	for _, e := range entities[:10] {
+		w.DelComp(e, position)
+	}
+	w.DelComp(entities[1], tag)
+	w.DelEntities(entities[15:]...)
+	w.Disable(entities[12])
+	if err := w.CheckInvariants(); err != nil {
+		t.Fatalf("CheckInvariants get: %v", err)
+	}
+
+	// Break the bookkeeping of the rows.
+	rec1, rec2 := w.Entities[entities[10]], w.Entities[entities[14]]
+	rec1.Row, rec2.Row = rec2.Row, rec1.Row
+	err := w.CheckInvariants()
+	if err == nil || !strings.Contains(err.Error(), "isn't recorded there") {
+		t.Errorf("CheckInvariants get: %v", err)
+	}
+	rec1.Row, rec2.Row = rec2.Row, rec1.Row
+
+	// Break a column.
+	rec := w.Entities[entities[10]]
+	col := w.Components[position][rec.AT]
+	*rec.AT.Comps[col].(*Table[int]) = (*rec.AT.Comps[col].(*Table[int]))[1:]
+	err = w.CheckInvariants()
+	if err == nil || !strings.Contains(err.Error(), "rows for") {
+		t.Errorf("CheckInvariants get: %v", err)
+	}
+}
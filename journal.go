@@ -0,0 +1,392 @@
+package ecs
+
+import (
+	"reflect"
+	"slices"
+)
+
+// A Journal records the changes to a World, so they can be undone and redone, like in editors.
+// It's created by World.StartJournal.
+//
+// The recorded operations are NewEntity, DelEntity, AddComp, SetComp, DelComp
+// and their variants like NewEntities, SetCompBulk, SetCompValue and SetCompField,
+// as well as SetName, Enable, Disable, EnableComp and DisableComp.
+// The data modified in place through pointers can't be restored.
+//
+// The operations are grouped into transactions by Begin and End,
+// and an operation outside of them is a transaction by itself, named by the operation.
+// Undo and Redo work on whole transactions.
+//
+// The values of the Components are copied by assignment,
+// so the memory referenced by the values, like the elements of slices, is not copied.
+type Journal struct {
+	w *World
+	// The maximum number of operations kept in the history.
+	limit int
+
+	undo, redo []*journalTx
+	// The transaction opened by Begin, and the depth of the nested Begin calls.
+	current *journalTx
+	depth   int
+	// The number of operations in undo.
+	size int
+}
+
+// journalTx is a transaction of a Journal.
+type journalTx struct {
+	name string
+	ops  []journalOp
+}
+
+// journalOp is a recorded operation, which can be undone and redone.
+type journalOp struct {
+	undo, redo func(w *World)
+}
+
+// StartJournal starts recording the changes to the World into a new Journal.
+// At most limit operations are kept in the history, and the oldest transactions are dropped when it's exceeded.
+// If limit <= 0, the history is unbounded.
+//
+// Only one Journal can record the World at a time, the previous one is stopped.
+func (w *World) StartJournal(limit int) *Journal {
+	w.journal = &Journal{w: w, limit: limit}
+	return w.journal
+}
+
+// StopJournal stops recording the changes. The recorded history can still be undone and redone.
+func (w *World) StopJournal() {
+	w.journal = nil
+}
+
+// Begin opens a transaction of the name. The operations until the matching End are undone and redone together.
+// Nested transactions are merged into the outermost one.
+func (j *Journal) Begin(name string) {
+	if j.depth == 0 {
+		j.current = &journalTx{name: name}
+	}
+	j.depth++
+}
+
+// End closes the transaction opened by Begin.
+func (j *Journal) End() {
+	if j.depth == 0 {
+		panic("ecs: Journal.End without Begin")
+	}
+	if j.depth--; j.depth == 0 {
+		tx := j.current
+		j.current = nil
+		if len(tx.ops) > 0 {
+			j.push(tx)
+		}
+	}
+}
+
+// Undo undoes the last transaction, and returns its name.
+// If there is nothing to undo, ok will be false.
+func (j *Journal) Undo() (name string, ok bool) {
+	if j.depth > 0 {
+		panic("ecs: Journal.Undo inside a transaction")
+	}
+	if len(j.undo) == 0 {
+		return "", false
+	}
+	tx := j.undo[len(j.undo)-1]
+	j.undo = j.undo[:len(j.undo)-1]
+	j.size -= len(tx.ops)
+	j.replay(func(w *World) {
+		for _, op := range slices.Backward(tx.ops) {
+			op.undo(w)
+		}
+	})
+	j.redo = append(j.redo, tx)
+	return tx.name, true
+}
+
+// Redo redoes the last undone transaction, and returns its name.
+// If there is nothing to redo, ok will be false.
+// The history to redo is cleared when any new operation is recorded.
+func (j *Journal) Redo() (name string, ok bool) {
+	if j.depth > 0 {
+		panic("ecs: Journal.Redo inside a transaction")
+	}
+	if len(j.redo) == 0 {
+		return "", false
+	}
+	tx := j.redo[len(j.redo)-1]
+	j.redo = j.redo[:len(j.redo)-1]
+	j.replay(func(w *World) {
+		for _, op := range tx.ops {
+			op.redo(w)
+		}
+	})
+	j.undo = append(j.undo, tx)
+	j.size += len(tx.ops)
+	return tx.name, true
+}
+
+// replay calls f without recording the changes.
+func (j *Journal) replay(f func(w *World)) {
+	recording := j.w.journal
+	j.w.journal = nil
+	defer func() { j.w.journal = recording }()
+	f(j.w)
+}
+
+// push adds the closed transaction to the history.
+func (j *Journal) push(tx *journalTx) {
+	j.undo = append(j.undo, tx)
+	j.size += len(tx.ops)
+	for j.limit > 0 && j.size > j.limit && len(j.undo) > 0 {
+		j.size -= len(j.undo[0].ops)
+		j.undo[0] = nil
+		j.undo = j.undo[1:]
+	}
+}
+
+// record adds the operations to the current transaction, or a new transaction of the name.
+func (j *Journal) record(name string, ops ...journalOp) {
+	clear(j.redo)
+	j.redo = j.redo[:0]
+	if j.current != nil {
+		j.current.ops = append(j.current.ops, ops...)
+		return
+	}
+	j.push(&journalTx{name: name, ops: ops})
+}
+
+// created records the creation of the Entity.
+func (j *Journal) created(name string, e Entity) {
+	var after *entitySnapshot
+	j.record(name, journalOp{
+		undo: func(w *World) {
+			after = w.snapshot(e)
+			w.erase(after)
+		},
+		redo: func(w *World) { w.restore(after) },
+	})
+}
+
+// deleting records the deletion of the Entity. It must be called before the Entity is deleted.
+func (j *Journal) deleting(e Entity) {
+	before := j.w.snapshot(e)
+	j.record("DelEntity", journalOp{
+		undo: func(w *World) { w.restore(before) },
+		redo: func(w *World) { w.DelEntity(e) },
+	})
+}
+
+// changing records the change of the Component c of the Entity. It must be called before the change.
+func (j *Journal) changing(name string, e Entity, c Component) {
+	before := j.w.compState(e, c)
+	var after compState
+	j.record(name, journalOp{
+		undo: func(w *World) {
+			after = w.compState(e, c)
+			w.setCompState(e, c, before)
+		},
+		redo: func(w *World) { w.setCompState(e, c, after) },
+	})
+}
+
+// toggling records the change of whether the Entity is enabled. It must be called before the change.
+func (j *Journal) toggling(name string, e Entity) {
+	before := j.w.IsEnabled(e)
+	var after bool
+	j.record(name, journalOp{
+		undo: func(w *World) {
+			after = w.IsEnabled(e)
+			w.setEnabled(e, before)
+		},
+		redo: func(w *World) { w.setEnabled(e, after) },
+	})
+}
+
+// renaming records the change of the name of the Entity. It must be called before the change.
+func (j *Journal) renaming(e Entity, name string) {
+	prev := j.w.nameOf[e]
+	holder, taken := j.w.Names[name]
+	j.record("SetName", journalOp{
+		undo: func(w *World) {
+			w.SetName(e, prev)
+			// Give the name back to the Entity it's taken from.
+			if taken && holder != e {
+				w.SetName(holder, name)
+			}
+		},
+		redo: func(w *World) { w.SetName(e, name) },
+	})
+}
+
+// resetting records the reset of src by World.Merge. It must be called before the reset.
+// Undoing it puts back the World src as it was before the Merge,
+// replacing whatever src is at that time.
+func (j *Journal) resetting(src *World) {
+	before := *src
+	j.record("Merge", journalOp{
+		undo: func(*World) { *src = before },
+		redo: func(*World) { *src = *NewWorld() },
+	})
+}
+
+// begin opens a transaction of the Journal if the World is recorded, and returns the function to close it.
+// It's used by the operations consisting of many operations, like NewEntities,
+// to make them a single transaction.
+func (w *World) begin(name string) (end func()) {
+	j := w.journal
+	if j == nil {
+		return func() {}
+	}
+	j.Begin(name)
+	return j.End
+}
+
+// entitySnapshot is the state of an Entity, which can be restored after the Entity is deleted.
+type entitySnapshot struct {
+	e        Entity
+	name     string
+	types    Types
+	values   []any // The data of each of the types, nil for the tags.
+	disabled bool
+	toggles  []bool // Whether each of the types is disabled.
+
+	// Whether the Entity is a Component, and the relationship it represents.
+	comp bool
+	pair *Pair
+}
+
+func (w *World) snapshot(e Entity) *entitySnapshot {
+	rec := w.Entities[e]
+	s := &entitySnapshot{
+		e:        e,
+		name:     w.nameOf[e],
+		types:    slices.Clone(rec.AT.Types),
+		values:   make([]any, len(rec.AT.Types)),
+		disabled: rec.AT.disabled.has(rec.Row),
+		toggles:  make([]bool, len(rec.AT.Types)),
+	}
+	for i, col := range rec.AT.Comps {
+		if col != nil {
+			s.values[i] = col.Get(rec.Row)
+		}
+		s.toggles[i] = rec.AT.toggles[i].has(rec.Row)
+	}
+	_, s.comp = w.Components[Component(e)]
+	if p, ok := w.pairOf[Component(e)]; ok {
+		s.pair = &p
+	}
+	return s
+}
+
+// restore recreates the Entity of the snapshot, with the same id.
+func (w *World) restore(s *entitySnapshot) {
+	w.revive(uint64(s.e))
+	a := w.archetype(slices.Clone(s.types))
+	r := &EntityRecord{AT: a}
+	w.touch(a)
+	r.Row = a.push(s.e, r)
+	w.Entities[s.e] = r
+	if s.disabled {
+		a.disabled.set(r.Row)
+	}
+	for i, col := range a.Comps {
+		if col != nil {
+			col.grow(1)
+			if v := reflect.ValueOf(s.values[i]); v.IsValid() {
+				reflect.ValueOf(col.ptr(r.Row)).Elem().Set(v)
+			}
+		}
+		if s.toggles[i] {
+			a.toggles[i].set(r.Row)
+		}
+	}
+	if s.name != "" {
+		w.SetName(s.e, s.name)
+	}
+	if _, ok := w.Components[Component(s.e)]; s.comp && !ok {
+		w.Components[Component(s.e)] = make(map[*Archetype]int)
+	}
+	if s.pair != nil {
+		w.Pairs[*s.pair] = Component(s.e)
+		w.pairOf[Component(s.e)] = *s.pair
+	}
+}
+
+// erase deletes the Entity of the snapshot, and if it's a Component, unregisters the Component
+// and deletes its archetypes, which are empty as the changes after its creation are undone.
+// If some of its archetypes are not empty, the Component is kept registered.
+func (w *World) erase(s *entitySnapshot) {
+	w.DelEntity(s.e)
+	if c := Component(s.e); s.comp {
+		for a := range w.Components[c] {
+			if len(a.entities) == 0 {
+				w.delArchetype(a)
+			}
+		}
+		if len(w.Components[c]) == 0 {
+			delete(w.Components, c)
+		}
+	}
+	if s.pair != nil {
+		delete(w.Pairs, *s.pair)
+		delete(w.pairOf, Component(s.e))
+	}
+}
+
+// revive takes the id out of the IDManager, so that the deleted Entity of the id can be recreated.
+func (i *IDManager) revive(id uint64) {
+	if k := slices.Index(i.Freelist, id); k != -1 {
+		i.Freelist = slices.Delete(i.Freelist, k, k+1)
+	} else if id >= i.NextID {
+		for next := i.NextID; next < id; next++ {
+			i.Freelist = append(i.Freelist, next)
+		}
+		i.NextID = id + 1
+	}
+}
+
+// compState is the state of a Component of an Entity.
+type compState struct {
+	has      bool
+	tag      bool // Whether the Entity has the Component as a tag, without data.
+	value    any  // The data, which may be nil for the data types like interfaces.
+	disabled bool
+}
+
+func (w *World) compState(e Entity, c Component) (s compState) {
+	rec := w.Entities[e]
+	col, ok := w.Components[c][rec.AT]
+	if !ok {
+		return
+	}
+	s.has, s.tag = true, col == -1
+	if !s.tag {
+		s.value = rec.AT.Comps[col].Get(rec.Row)
+	}
+	s.disabled = rec.AT.toggles[rec.AT.index(c)].has(rec.Row)
+	return
+}
+
+func (w *World) setEnabled(e Entity, enabled bool) {
+	if enabled {
+		w.Enable(e)
+	} else {
+		w.Disable(e)
+	}
+}
+
+func (w *World) setCompState(e Entity, c Component, s compState) {
+	switch {
+	case !s.has:
+		w.DelComp(e, c)
+		return
+	case s.tag:
+		w.AddComp(e, c)
+	default:
+		w.SetCompValue(e, c, s.value)
+	}
+	if s.disabled {
+		w.DisableComp(e, c)
+	} else {
+		w.EnableComp(e, c)
+	}
+}
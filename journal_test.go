@@ -0,0 +1,172 @@
+package ecs
+
+import (
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	type Position struct{ X, Y int }
+
+	w := NewWorld()
+	position := w.NewComponent()
+	selected := w.NewComponent()
+	j := w.StartJournal(0)
+
+	e := w.NewEntity()
+	w.SetName(e, "hero")
+
+	j.Begin("Move")
+	w.SetComp(e, position, Position{1, 2})
+	w.SetComp(e, position, Position{3, 4})
+	w.AddComp(e, selected)
+	j.End()
+
+	w.DelComp(e, selected)
+	if err := w.SetCompField(e, position, "X", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(t *testing.T, want *Position, wantSelected bool) {
+		t.Helper()
+		if got := w.GetComp[Position](e, position); (got == nil) != (want == nil) || got != nil && *got != *want {
+			t.Errorf("Position get: %v, want: %v", got, want)
+		}
+		if got := w.HasComp(e, selected); got != wantSelected {
+			t.Errorf("selected get: %v, want: %v", got, wantSelected)
+		}
+		if err := w.CheckInvariants(); err != nil {
+			t.Error(err)
+		}
+	}
+	check(t, &Position{5, 4}, false)
+
+	for _, want := range []string{"SetCompField", "DelComp", "Move"} {
+		if name, ok := j.Undo(); !ok || name != want {
+			t.Errorf("Undo get: %q, %v, want: %q", name, ok, want)
+		}
+	}
+	check(t, nil, false)
+
+	if name, ok := j.Redo(); !ok || name != "Move" {
+		t.Errorf("Redo get: %q, %v, want: Move", name, ok)
+	}
+	check(t, &Position{3, 4}, true)
+
+	// A new operation clears the history to redo.
+	w.DelEntity(e)
+	if _, ok := j.Redo(); ok {
+		t.Errorf("Redo after a new operation")
+	}
+	if _, ok := w.Entities[e]; ok {
+		t.Fatalf("the entity isn't deleted")
+	}
+	j.Undo() // DelEntity
+	check(t, &Position{3, 4}, true)
+	if got, _ := w.Lookup("hero"); got != e {
+		t.Errorf("the name isn't restored")
+	}
+
+	j.Undo() // Move
+	if name, _ := j.Undo(); name != "SetName" || w.Name(e) != "" {
+		t.Errorf("Undo get: %q, and the name is %q", name, w.Name(e))
+	}
+	j.Undo() // NewEntity
+	if _, ok := w.Entities[e]; ok {
+		t.Errorf("the entity isn't deleted by undoing NewEntity")
+	}
+	if _, ok := j.Undo(); ok {
+		t.Errorf("Undo get: true, want: false")
+	}
+	j.Redo() // NewEntity
+	if _, ok := w.Entities[e]; !ok {
+		t.Errorf("the entity isn't recreated with the same id")
+	}
+	if err := w.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestJournal_bulk(t *testing.T) {
+	w := NewWorld()
+	c := w.NewComponent()
+	rel := w.NewComponent()
+	j := w.StartJournal(0)
+
+	entities := w.NewEntities(5, MetaOf[int](c))
+	w.SetCompBulk(entities, c, []int{1, 2, 3, 4, 5})
+	w.AddComp(entities[0], w.Pair(rel, entities[1])) // creates the pair Component
+	clone := w.Clone(entities[0])
+	w.DelEntities(entities[2:]...)
+
+	for _, want := range []string{"DelEntities", "Clone", "AddComp", "NewEntity", "SetCompBulk", "NewEntities"} {
+		if name, ok := j.Undo(); !ok || name != want {
+			t.Errorf("Undo get: %q, want: %q", name, want)
+		}
+		if err := w.CheckInvariants(); err != nil {
+			t.Fatalf("after undoing %s: %v", want, err)
+		}
+	}
+	if len(w.Entities) != 2 || len(w.Pairs) != 0 {
+		t.Errorf("undo all get: %d entities and %d pairs, want: 2 and 0", len(w.Entities), len(w.Pairs))
+	}
+
+	for range 6 {
+		j.Redo()
+	}
+	if err := w.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+	if got := *w.GetComp[int](clone, c); got != 1 {
+		t.Errorf("the clone get: %d, want: 1", got)
+	}
+	if targets := w.Targets(clone, rel); len(targets) != 1 || targets[0] != entities[1] {
+		t.Errorf("the targets of the clone get: %v", targets)
+	}
+	if _, ok := w.Entities[entities[2]]; ok {
+		t.Errorf("the deleted entity is alive after redo")
+	}
+}
+
+func TestJournal_nilData(t *testing.T) {
+	w := NewWorld()
+	c := w.NewComponent()
+	e := w.NewEntity()
+	w.SetComp[any](e, c, nil)
+	j := w.StartJournal(0)
+
+	w.SetComp[any](e, c, "x")
+	j.Undo()
+	if err := w.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+	if p := w.GetComp[any](e, c); p == nil || *p != nil {
+		t.Errorf("undo get: %v, want: nil data", p)
+	}
+	j.Redo()
+	if p := w.GetComp[any](e, c); p == nil || *p != "x" {
+		t.Errorf("redo get: %v, want: x", p)
+	}
+}
+
+func TestJournal_limit(t *testing.T) {
+	w := NewWorld()
+	c := w.NewComponent()
+	j := w.StartJournal(3)
+	e := w.NewEntity()
+	for i := range 5 {
+		w.SetComp(e, c, i)
+	}
+	undone := 0
+	for {
+		if _, ok := j.Undo(); !ok {
+			break
+		}
+		undone++
+	}
+	if undone != 3 {
+		t.Errorf("undone %d transactions, want: 3", undone)
+	}
+	if got := *w.GetComp[int](e, c); got != 1 {
+		t.Errorf("get: %d, want: 1", got)
+	}
+}
@@ -0,0 +1,213 @@
+package ecs
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A RemapFunc fixes the references to entities inside the data of the Component c,
+// after the data is moved to another World by World.Merge.
+//
+// The data is the pointer to the moved data, of type *T where T is the data type of the Component.
+// The entity function maps the entities of the source World to the destination World.
+// The entities not belonging to the source World are returned as is.
+type RemapFunc func(c Component, data any, entity func(Entity) Entity)
+
+// Merge moves all entities and their Components from src into w,
+// and returns the map from the entities of src to their new ids in w.
+// It can be used to prepare entities in a separate World, for example in a background goroutine,
+// and add them to the main World at once.
+//
+// The Components named by SetName are identified by their names,
+// so they are mapped to the Components of w with the same names.
+// Other Components are created in w as new Components, with their names kept.
+// The relationships are mapped to the same kind of relationships to the mapped targets.
+// Named entities that are not Components are always moved as new entities,
+// and their names are kept only if the names are not used in w.
+//
+// The data is moved as is. If it contains Entity or Component values,
+// the remap function should be given to fix them, otherwise it can be nil.
+//
+// If a Component mapped to an existing Component of w has a different data type, or is a tag in one World
+// but has data in the other, an error is returned and neither World is changed.
+// The relationships whose kind or target is deleted in src are dropped from the entities.
+//
+// After merging, src is reset to an empty World.
+// The queries cached on src are no longer updated.
+// If w is recorded by a Journal or in a Tx, undoing the Merge puts back src as it was.
+func (w *World) Merge(src *World, remap RemapFunc) (entities map[Entity]Entity, err error) {
+	// The Components of src with the same names as the Components of w.
+	same := make(map[Entity]Entity)
+	for e, name := range src.nameOf {
+		if _, isComp := src.Components[Component(e)]; !isComp {
+			continue
+		}
+		if e2, ok := w.Names[name]; ok {
+			if _, ok := w.Components[Component(e2)]; ok {
+				same[e] = e2
+			}
+		}
+	}
+	if err = w.checkMerge(src, same); err != nil {
+		return nil, err
+	}
+
+	defer w.begin("Merge")()
+	entities = make(map[Entity]Entity, len(src.Entities))
+	// The entities which already exist in w, and their data won't be moved.
+	existing := make(map[Entity]bool)
+
+	// Map the entities except the relationships, whose targets have to be mapped first.
+	for e := range src.Entities {
+		if _, ok := src.pairOf[Component(e)]; ok {
+			continue
+		}
+		if e2, ok := same[e]; ok {
+			entities[e] = e2
+			existing[e] = true
+			continue
+		}
+		newEntity := Entity(w.get())
+		if _, isComp := src.Components[Component(e)]; isComp {
+			w.Components[Component(newEntity)] = make(map[*Archetype]int)
+		}
+		entities[e] = newEntity
+	}
+	// The kind or target of a relationship might be another relationship,
+	// so map them until nothing more can be mapped. The rest are dropped.
+	for mapped := true; mapped; {
+		mapped = false
+		for c, p := range src.pairOf {
+			if _, ok := entities[Entity(c)]; ok {
+				continue
+			}
+			rel, ok1 := entities[Entity(p.Rel)]
+			target, ok2 := entities[p.Target]
+			if ok1 && ok2 {
+				entities[Entity(c)] = Entity(w.Pair(Component(rel), target))
+				existing[Entity(c)] = true
+				mapped = true
+			}
+		}
+	}
+	for e, name := range src.nameOf {
+		if _, ok := w.Names[name]; !ok && !existing[e] {
+			if mapped, ok := entities[e]; ok {
+				w.SetName(mapped, name)
+			}
+		}
+	}
+	mapEntity := func(e Entity) Entity {
+		if mapped, ok := entities[e]; ok {
+			return mapped
+		}
+		return e
+	}
+
+	// Move the data, archetype by archetype.
+	for _, a := range src.Archetypes {
+		if len(a.entities) == 0 {
+			continue
+		}
+		types := make(Types, 0, len(a.Types))
+		for _, t := range a.Types {
+			if c, ok := entities[Entity(t.Component)]; ok {
+				types = append(types, ComponentMeta{Component: Component(c), TableType: t.TableType})
+			}
+		}
+		dst := w.archetype(types)
+		// The column in dst for each of the Types in a, or -1 for the dropped relationships.
+		columns := make([]int, len(a.Types))
+		for i, t := range a.Types {
+			columns[i] = -1
+			if c, ok := entities[Entity(t.Component)]; ok {
+				columns[i] = dst.index(Component(c))
+			}
+		}
+
+		for row, e := range a.entities {
+			if _, ok := entities[e]; !ok || existing[e] {
+				continue
+			}
+			r := &EntityRecord{AT: dst}
+			w.touch(dst)
+			r.Row = dst.push(entities[e], r)
+			w.Entities[entities[e]] = r
+			if w.journal != nil {
+				w.journal.created("Merge", entities[e])
+			}
+			if a.disabled.has(row) {
+				dst.disabled.set(r.Row)
+			}
+			for i, s := range a.Comps {
+				j := columns[i]
+				if j == -1 {
+					continue
+				}
+				if s != nil {
+					dst.Comps[j].appendFrom(s, row)
+					if remap != nil {
+						remap(dst.Types[j].Component, dst.Comps[j].ptr(r.Row), mapEntity)
+					}
+				}
+				if a.toggles[i].has(row) {
+					dst.toggles[j].set(r.Row)
+				}
+			}
+		}
+	}
+
+	if w.journal != nil {
+		w.journal.resetting(src)
+	}
+	*src = *NewWorld()
+	return
+}
+
+// checkMerge checks that the Components of src mapped to the existing Components of w have the same data types,
+// including the relationships between them.
+func (w *World) checkMerge(src *World, same map[Entity]Entity) error {
+	check := func(c, c2 Component) error {
+		t, ok := src.storeType(c)
+		t2, ok2 := w.storeType(c2)
+		if ok && ok2 && t != t2 {
+			return fmt.Errorf("ecs: can't merge Component %s of %s into %s", src.label(Entity(c)), typeName(t), typeName(t2))
+		}
+		return nil
+	}
+	for e, e2 := range same {
+		if err := check(Component(e), Component(e2)); err != nil {
+			return err
+		}
+	}
+	for c, p := range src.pairOf {
+		rel, ok1 := same[Entity(p.Rel)]
+		target, ok2 := same[p.Target]
+		if !ok1 || !ok2 {
+			continue
+		}
+		if c2, ok := w.Pairs[Pair{Rel: Component(rel), Target: target}]; ok {
+			if err := check(c, c2); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// storeType returns the TableType of the Component in the archetypes having it, which is nil for tags.
+// If no archetype has the Component, ok will be false.
+func (w *World) storeType(c Component) (t reflect.Type, ok bool) {
+	for a := range w.Components[c] {
+		return a.Types[a.index(c)].TableType, true
+	}
+	return nil, false
+}
+
+// typeName returns the name of the data type of the TableType.
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return "tag"
+	}
+	return "data " + t.Elem().Elem().String()
+}
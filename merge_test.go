@@ -0,0 +1,172 @@
+package ecs
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWorld_Merge(t *testing.T) {
+	type Target struct{ Entity Entity }
+
+	dst := NewWorld()
+	position := dst.NewComponent()
+	dst.SetName(Entity(position), "Position")
+	follow := dst.NewComponent()
+	dst.SetName(Entity(follow), "Follow")
+	childOf := dst.NewComponent()
+	dst.SetName(Entity(childOf), "ChildOf")
+	player := dst.NewEntity()
+	dst.SetName(player, "player")
+	dst.SetComp(player, position, 1)
+
+	// The src world has different ids for the same Components.
+	src := NewWorld()
+	src.NewEntity()
+	srcChildOf := src.NewComponent()
+	src.SetName(Entity(srcChildOf), "ChildOf")
+	srcFollow := src.NewComponent()
+	src.SetName(Entity(srcFollow), "Follow")
+	srcPosition := src.NewComponent()
+	src.SetName(Entity(srcPosition), "Position")
+	health := src.NewComponent()
+	src.SetName(Entity(health), "Health")
+
+	chunk := src.NewEntity()
+	src.SetName(chunk, "chunk")
+	enemy := src.NewEntity()
+	src.SetName(enemy, "player") // conflicts with dst
+	src.SetComp(enemy, srcPosition, 2)
+	src.SetComp(enemy, health, 100)
+	src.AddComp(enemy, src.Pair(srcChildOf, chunk))
+	pet := src.NewEntity()
+	src.SetComp(pet, srcPosition, 3)
+	src.SetComp(pet, srcFollow, Target{enemy})
+	src.Disable(pet)
+
+	remapped := 0
+	entities, err := dst.Merge(src, func(c Component, data any, entity func(Entity) Entity) {
+		if c == follow {
+			t := data.(*Target)
+			t.Entity = entity(t.Entity)
+			remapped++
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remapped != 1 {
+		t.Errorf("remap is called %d times, want 1", remapped)
+	}
+	if len(src.Entities) != 0 {
+		t.Errorf("src isn't empty after merging")
+	}
+
+	if entities[Entity(srcPosition)] != Entity(position) {
+		t.Errorf("the Components with the same name aren't mapped to the same Component")
+	}
+	newHealth, ok := dst.Lookup("Health")
+	if !ok || Component(newHealth) != Component(entities[Entity(health)]) {
+		t.Fatalf("the new Component isn't named")
+	}
+	if e, _ := dst.Lookup("player"); e != player {
+		t.Errorf("the name of existing entity is taken away")
+	}
+	if e, _ := dst.Lookup("chunk"); e != entities[chunk] {
+		t.Errorf("the name of moved entity isn't kept")
+	}
+
+	var result []int
+	dst.Query(WithDisabled(QueryAll(position)), func(entities []Entity, data []any) {
+		result = append(result, *data[0].(*[]int)...)
+	})
+	sort.Ints(result)
+	if len(result) != 3 || result[0] != 1 || result[1] != 2 || result[2] != 3 {
+		t.Errorf("positions: %v, want: [1 2 3]", result)
+	}
+
+	if got := *dst.GetComp[int](entities[enemy], Component(newHealth)); got != 100 {
+		t.Errorf("health: %v, want: 100", got)
+	}
+	if targets := dst.Targets(entities[enemy], childOf); len(targets) != 1 || targets[0] != entities[chunk] {
+		t.Errorf("targets: %v, want: [%v]", targets, entities[chunk])
+	}
+	if got := *dst.GetComp[Target](entities[pet], follow); got.Entity != entities[enemy] {
+		t.Errorf("follow: %v, want: %v", got.Entity, entities[enemy])
+	}
+	if dst.IsEnabled(entities[pet]) {
+		t.Errorf("the disabled entity is enabled after merging")
+	}
+	if err := dst.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWorld_Merge_deletedTarget(t *testing.T) {
+	src := NewWorld()
+	childOf := src.NewComponent()
+	parent := src.NewEntity()
+	child := src.NewEntity()
+	src.AddComp(child, src.Pair(childOf, parent))
+	src.SetComp(child, childOf, 1) // Also uses the kind as a Component
+	src.DelEntity(parent)
+
+	dst := NewWorld()
+	zero := dst.NewEntity()
+	entities, err := dst.Merge(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sources := dst.Sources(Component(entities[Entity(childOf)]), zero); len(sources) != 0 {
+		t.Errorf("the relationship to the deleted parent is mapped to %v", zero)
+	}
+	if types := dst.Entities[entities[child]].AT.Types; len(types) != 1 || types[0].Component != Component(entities[Entity(childOf)]) {
+		t.Errorf("the child get: %s, want only the data of ChildOf", dst.typesLabel(types))
+	}
+	if err := dst.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWorld_Merge_typeMismatch(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		dst, src func(w *World, e Entity, c Component)
+	}{
+		{
+			name: "tag and data",
+			dst:  func(w *World, e Entity, c Component) { w.AddComp(e, c) },
+			src:  func(w *World, e Entity, c Component) { w.SetComp(e, c, 1) },
+		},
+		{
+			name: "different data types",
+			dst:  func(w *World, e Entity, c Component) { w.SetComp(e, c, "a") },
+			src:  func(w *World, e Entity, c Component) { w.SetComp(e, c, 1) },
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dst := NewWorld()
+			c := dst.NewComponent()
+			dst.SetName(Entity(c), "C")
+			test.dst(dst, dst.NewEntity(), c)
+
+			src := NewWorld()
+			srcC := src.NewComponent()
+			src.SetName(Entity(srcC), "C")
+			test.src(src, src.NewEntity(), srcC)
+			src.SetName(src.NewEntity(), "named")
+
+			ids := dst.IDManager
+			count, srcCount := len(dst.Entities), len(src.Entities)
+			if _, err := dst.Merge(src, nil); err == nil {
+				t.Fatal("Merge doesn't return an error")
+			}
+			if len(dst.Entities) != count || !reflect.DeepEqual(dst.IDManager, ids) || len(dst.Names) != 1 {
+				t.Errorf("dst is changed")
+			}
+			if len(src.Entities) != srcCount {
+				t.Errorf("src is changed")
+			}
+		})
+	}
+}
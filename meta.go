@@ -0,0 +1,254 @@
+package ecs
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TypeMeta is the metadata of the data type of Components, for tools like editors and serializers.
+type TypeMeta struct {
+	Type   reflect.Type
+	Fields []FieldMeta // The exported fields if Type is a struct, otherwise empty.
+}
+
+// FieldMeta is the metadata of a field of a struct.
+//
+// The Unit, Min and Max are derived from the struct tag, for example:
+//
+//	type Health struct {
+//		HP    float64 `ecs:"min=0,max=100"`
+//		Regen float64 `ecs:"unit=HP/s"`
+//		Cache []int   `ecs:"-"` // The field is ignored
+//	}
+//
+// or registered by World.RegisterMeta.
+type FieldMeta struct {
+	Name   string
+	Type   reflect.Type
+	Offset uintptr
+	Unit   string
+	// The range of the value. A nil pointer means no limit.
+	Min, Max *float64
+}
+
+// RegisterMeta registers the metadata of the fields of the struct type T, which overrides the struct tags.
+// Only the Name, Unit, Min and Max of the fields are used, the Type and Offset are filled by reflection.
+// The fields not given keep the metadata derived from the struct tags.
+//
+// This function panics if T isn't a struct, or has no exported field of a name.
+func (w *World) RegisterMeta[T any](fields ...FieldMeta) {
+	m := w.typeMeta(reflect.TypeFor[T]())
+	for _, f := range fields {
+		i := m.field(f.Name)
+		if i == -1 {
+			panic(fmt.Sprintf("ecs: %v has no exported field %s", m.Type, f.Name))
+		}
+		m.Fields[i].Unit, m.Fields[i].Min, m.Fields[i].Max = f.Unit, f.Min, f.Max
+	}
+}
+
+// CompMeta returns the metadata of the data type of the Component,
+// or nil if the data type is unknown, see World.CompType.
+func (w *World) CompMeta(c Component) *TypeMeta {
+	t := w.CompType(c)
+	if t == nil {
+		return nil
+	}
+	return w.typeMeta(t)
+}
+
+// typeMeta returns the metadata of the type t, which is derived from the struct tags the first time.
+func (w *World) typeMeta(t reflect.Type) *TypeMeta {
+	if m, ok := w.metas[t]; ok {
+		return m
+	}
+	m := &TypeMeta{Type: t}
+	if t.Kind() == reflect.Struct {
+		for f := range t.Fields() {
+			tag := f.Tag.Get("ecs")
+			if !f.IsExported() || tag == "-" {
+				continue
+			}
+			fm := FieldMeta{Name: f.Name, Type: f.Type, Offset: f.Offset}
+			for opt := range strings.SplitSeq(tag, ",") {
+				key, value, _ := strings.Cut(opt, "=")
+				switch key {
+				case "unit":
+					fm.Unit = value
+				case "min", "max":
+					v, err := strconv.ParseFloat(value, 64)
+					if err != nil {
+						panic(fmt.Sprintf("ecs: invalid %s of %v.%s: %v", key, t, f.Name, err))
+					}
+					if key == "min" {
+						fm.Min = &v
+					} else {
+						fm.Max = &v
+					}
+				}
+			}
+			m.Fields = append(m.Fields, fm)
+		}
+	}
+	w.metas[t] = m
+	return m
+}
+
+// field returns the index of the field of the name in Fields, or -1 if it doesn't exist.
+func (m *TypeMeta) field(name string) int {
+	for i, f := range m.Fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetCompValue returns the data of the Component of the Entity, without knowing its type at compile time.
+// The returned reflect.Value is addressable, and setting it modifies the data in place,
+// in which case World.Modified should be called.
+//
+// If the Entity doesn't have the Component, or the Component is a tag, the zero reflect.Value is returned.
+func (w *World) GetCompValue(e Entity, c Component) reflect.Value {
+	rec := w.Entities[e]
+	if col, ok := w.Components[c][rec.AT]; ok && col != -1 {
+		w.touch(rec.AT)
+		return reflect.ValueOf(rec.AT.Comps[col].ptr(rec.Row)).Elem()
+	}
+	return reflect.Value{}
+}
+
+// SetCompField sets the field of the data of the Component of the Entity.
+// The field is the name of an exported field, and the fields of nested structs are separated by dots, like "Pos.X".
+//
+// The value is converted to the type of the field if both are numbers, otherwise it must be assignable to the field.
+// The numbers which overflow the field, or lose their fractional part in the conversion, are rejected.
+// If the field has a range in its metadata, the value out of the range is rejected.
+func (w *World) SetCompField(e Entity, c Component, field string, value any) error {
+	data := w.GetCompValue(e, c)
+	if !data.IsValid() {
+		return fmt.Errorf("ecs: entity %d doesn't have the data of Component %s", e, w.label(Entity(c)))
+	}
+
+	// Walk down to the field, and keep the metadata of the innermost struct.
+	f := data
+	var meta *TypeMeta
+	var name string
+	for name = range strings.SplitSeq(field, ".") {
+		if f.Kind() != reflect.Struct {
+			return fmt.Errorf("ecs: %v isn't a struct for field %s", f.Type(), field)
+		}
+		sf, ok := f.Type().FieldByName(name)
+		if !ok || !sf.IsExported() {
+			return fmt.Errorf("ecs: %v has no exported field %s", f.Type(), name)
+		}
+		meta = w.typeMeta(f.Type())
+		f = f.FieldByIndex(sf.Index)
+	}
+
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return fmt.Errorf("ecs: can't set field %s to nil", field)
+	}
+	// The range is checked on the value given, before it's converted to the field type.
+	if i := meta.field(name); i != -1 && isNumber(v.Kind()) {
+		fm := meta.Fields[i]
+		n := numberValue(v)
+		if fm.Min != nil && n < *fm.Min || fm.Max != nil && n > *fm.Max {
+			return fmt.Errorf("ecs: %v is out of the range of field %s", value, field)
+		}
+	}
+	switch {
+	case isNumber(v.Kind()) && isNumber(f.Kind()):
+		var ok bool
+		if v, ok = convertNumber(v, f.Type()); !ok {
+			return fmt.Errorf("ecs: %v can't be represented by field %s of type %v", value, field, f.Type())
+		}
+	case !v.Type().AssignableTo(f.Type()):
+		return fmt.Errorf("ecs: can't set field %s of type %v to %v", field, f.Type(), v.Type())
+	}
+
+	if w.journal != nil {
+		w.journal.changing("SetCompField", e, c)
+	}
+	f.Set(v)
+	w.Modified(e, c)
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	return reflect.Int <= k && k <= reflect.Float64
+}
+
+// numberValue returns the number v as a float64.
+func numberValue(v reflect.Value) float64 {
+	switch {
+	case v.CanInt():
+		return float64(v.Int())
+	case v.CanUint():
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
+
+// convertNumber converts the number v to the number type t.
+// It reports false if v overflows t, or t is an integer type and v has a fractional part.
+func convertNumber(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	out := reflect.New(t).Elem()
+	switch {
+	case out.CanInt():
+		var n int64
+		switch {
+		case v.CanInt():
+			n = v.Int()
+		case v.CanUint():
+			if v.Uint() > math.MaxInt64 {
+				return out, false
+			}
+			n = int64(v.Uint())
+		default:
+			// NaN fails the first comparison, and the infinities the others.
+			x := v.Float()
+			if x != math.Trunc(x) || x < -1<<63 || x >= 1<<63 {
+				return out, false
+			}
+			n = int64(x)
+		}
+		if out.OverflowInt(n) {
+			return out, false
+		}
+		out.SetInt(n)
+	case out.CanUint():
+		var n uint64
+		switch {
+		case v.CanInt():
+			if v.Int() < 0 {
+				return out, false
+			}
+			n = uint64(v.Int())
+		case v.CanUint():
+			n = v.Uint()
+		default:
+			x := v.Float()
+			if x != math.Trunc(x) || x < 0 || x >= 1<<64 {
+				return out, false
+			}
+			n = uint64(x)
+		}
+		if out.OverflowUint(n) {
+			return out, false
+		}
+		out.SetUint(n)
+	default:
+		x := numberValue(v)
+		if out.OverflowFloat(x) {
+			return out, false
+		}
+		out.SetFloat(x)
+	}
+	return out, true
+}
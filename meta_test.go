@@ -0,0 +1,101 @@
+package ecs
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorld_CompMeta(t *testing.T) {
+	type Health struct {
+		HP    float64 `ecs:"min=0,max=100"`
+		Regen float64 `ecs:"unit=HP/s"`
+		Cache []int   `ecs:"-"`
+		dirty bool
+	}
+
+	w := NewWorld()
+	health := w.NewComponent()
+	tag := w.NewComponent()
+	e := w.NewEntity()
+	w.SetComp(e, health, Health{HP: 50})
+	w.AddComp(e, tag)
+
+	m := w.CompMeta(health)
+	if m == nil || m.Type != reflect.TypeFor[Health]() || len(m.Fields) != 2 {
+		t.Fatalf("CompMeta get: %+v", m)
+	}
+	if hp := m.Fields[0]; hp.Name != "HP" || hp.Type.Kind() != reflect.Float64 || *hp.Min != 0 || *hp.Max != 100 {
+		t.Errorf("the metadata of HP get: %+v", hp)
+	}
+	if regen := m.Fields[1]; regen.Unit != "HP/s" || regen.Offset != 8 || regen.Min != nil {
+		t.Errorf("the metadata of Regen get: %+v", regen)
+	}
+	if w.CompMeta(tag) != nil {
+		t.Errorf("CompMeta of a tag isn't nil")
+	}
+
+	limit := 200.0
+	w.RegisterMeta[Health](FieldMeta{Name: "HP", Unit: "HP", Max: &limit})
+	if hp := w.CompMeta(health).Fields[0]; hp.Unit != "HP" || *hp.Max != 200 || hp.Min != nil {
+		t.Errorf("the registered metadata of HP get: %+v", hp)
+	}
+}
+
+func TestWorld_SetCompField(t *testing.T) {
+	type Vec struct{ X, Y int }
+	type Transform struct {
+		Pos   Vec
+		Scale float32 `ecs:"min=0"`
+		Name  string
+		Alpha uint8 `ecs:"max=100"`
+		Layer uint8
+	}
+
+	w := NewWorld()
+	transform := w.NewComponent()
+	e := w.NewEntity()
+	w.SetComp(e, transform, Transform{Scale: 1})
+
+	if err := w.SetCompField(e, transform, "Pos.X", 3.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetCompField(e, transform, "Scale", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetCompField(e, transform, "Name", "hero"); err != nil {
+		t.Fatal(err)
+	}
+	want := Transform{Pos: Vec{X: 3}, Scale: 2, Name: "hero"}
+	if got := w.GetCompValue(e, transform).Interface(); got != want {
+		t.Errorf("get: %+v, want: %+v", got, want)
+	}
+
+	for _, c := range []struct {
+		field string
+		value any
+		err   string
+	}{
+		{"Scale", -1, "out of the range"},
+		{"Alpha", 300, "out of the range"},
+		{"Layer", 300, "can't be represented"},
+		{"Layer", -1, "can't be represented"},
+		{"Pos.X", 1.5, "can't be represented"},
+		{"Pos.X", math.NaN(), "can't be represented"},
+		{"Scale", 1e39, "can't be represented"},
+		{"Name", 1, "can't set"},
+		{"Pos.Z", 1, "no exported field"},
+		{"Name.X", 1, "isn't a struct"},
+	} {
+		if err := w.SetCompField(e, transform, c.field, c.value); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("SetCompField(%s, %v) get: %v, want: %s", c.field, c.value, err, c.err)
+		}
+	}
+	if got := w.GetCompValue(e, transform).Interface(); got != want {
+		t.Errorf("the data is modified by the failed calls: %+v", got)
+	}
+	if v := w.GetCompValue(w.NewEntity(), transform); v.IsValid() {
+		t.Errorf("GetCompValue of an entity without the Component is valid")
+	}
+}
@@ -0,0 +1,66 @@
+package ecs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SetName gives the Entity a name, which is unique in the World and can be used to look up the Entity.
+// If the name is used by another Entity, it's taken away from that Entity.
+// An empty name removes the Entity's name.
+//
+// The names of Components are their identities across worlds,
+// which are used by World.Merge to tell which Components are the same.
+func (w *World) SetName(e Entity, name string) {
+	if w.journal != nil {
+		w.journal.renaming(e, name)
+	}
+	w.delName(e)
+	if name == "" {
+		return
+	}
+	if old, ok := w.Names[name]; ok {
+		delete(w.nameOf, old)
+	}
+	w.Names[name] = e
+	w.nameOf[e] = name
+}
+
+// Name returns the name of the Entity, or an empty string if it doesn't have one.
+func (w *World) Name(e Entity) string {
+	return w.nameOf[e]
+}
+
+// Lookup finds the Entity by its name.
+func (w *World) Lookup(name string) (e Entity, ok bool) {
+	e, ok = w.Names[name]
+	return
+}
+
+func (w *World) delName(e Entity) {
+	if name, ok := w.nameOf[e]; ok {
+		delete(w.Names, name)
+		delete(w.nameOf, e)
+	}
+}
+
+// label returns a readable name of the Entity for debugging,
+// which is its name, (Rel, Target) for relationships, or #id if it doesn't have a name.
+func (w *World) label(e Entity) string {
+	if name, ok := w.nameOf[e]; ok {
+		return name
+	}
+	if p, ok := w.pairOf[Component(e)]; ok {
+		return "(" + w.label(Entity(p.Rel)) + ", " + w.label(p.Target) + ")"
+	}
+	return "#" + strconv.FormatUint(uint64(e), 10)
+}
+
+// typesLabel returns the labels of the Types in the form of [A, B, C].
+func (w *World) typesLabel(t Types) string {
+	names := make([]string, len(t))
+	for i, c := range t {
+		names[i] = w.label(Entity(c.Component))
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
@@ -0,0 +1,66 @@
+package ecs
+
+import (
+	"runtime"
+	"sync"
+)
+
+// ParallelRun is like Run, but splits the rows of each archetype into chunks of at most chunkSize rows,
+// and calls the handler for the chunks concurrently from the workers goroutines.
+// It returns after all the chunks are processed.
+//
+// Each call of the handler gets the sub-slices of the entities and the columns of its chunk.
+// The handler must not modify the World, and should only write to the data of its own chunk.
+//
+// If workers <= 0, GOMAXPROCS is used. If chunkSize <= 0, the rows are not split.
+func (q *CachedQuery) ParallelRun(workers, chunkSize int, h func(entities []Entity, data []any)) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	type chunk struct {
+		a          *Archetype
+		m          *Match
+		start, end int
+	}
+	// The handler is called concurrently, so the data is touched in advance, see World.touch.
+	for i, a := range q.tables[:q.active] {
+		q.world.touch(a)
+		q.matches[i].touchSources(q.world)
+	}
+
+	chunks := make(chan chunk, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			var data []any
+			for c := range chunks {
+				data = c.m.appendSlices(q.world, data[:0], c.a, c.start, c.end)
+				h(c.a.entities[c.start:c.end], data)
+			}
+		})
+	}
+
+	split := func(a *Archetype, m *Match, start, end int) {
+		for start < end {
+			next := end
+			if chunkSize > 0 {
+				next = min(start+chunkSize, end)
+			}
+			chunks <- chunk{a, m, start, next}
+			start = next
+		}
+	}
+	for i, a := range q.tables[:q.active] {
+		m := &q.matches[i]
+		if !m.masked(a) {
+			split(a, m, 0, len(a.entities))
+			continue
+		}
+		for start, end := range m.spans(a) {
+			split(a, m, start, end)
+		}
+	}
+	close(chunks)
+	wg.Wait()
+}
@@ -0,0 +1,88 @@
+package ecs
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestCachedQuery_ParallelRun(t *testing.T) {
+	type Position struct{ x, y float64 }
+	type Velocity struct{ x, y float64 }
+
+	w := NewWorld()
+	position := w.NewComponent()
+	velocity := w.NewComponent()
+	tag := w.NewComponent()
+
+	entities := w.NewEntities(1000, MetaOf[Position](position), MetaOf[Velocity](velocity))
+	entities = append(entities, w.NewEntities(500, MetaOf[Position](position), MetaOf[Velocity](velocity), ComponentMeta{Component: tag})...)
+	for i, e := range entities {
+		w.SetComp(e, velocity, Velocity{float64(i), 1})
+	}
+	for _, e := range entities[100:200] {
+		w.Disable(e)
+	}
+
+	q := w.Cache(QueryAll(position, velocity))
+	var rows, calls atomic.Int64
+	q.ParallelRun(4, 64, func(entities []Entity, data []any) {
+		p := *data[0].(*[]Position)
+		v := *data[1].(*[]Velocity)
+		if len(p) != len(entities) || len(v) != len(entities) || len(entities) > 64 {
+			t.Errorf("chunk size mismatch: %d entities, %d positions, %d velocities", len(entities), len(p), len(v))
+		}
+		for i := range entities {
+			p[i].x += v[i].x
+			p[i].y += v[i].y
+		}
+		rows.Add(int64(len(entities)))
+		calls.Add(1)
+	})
+
+	if got := rows.Load(); got != 1400 {
+		t.Errorf("rows: %d, want: 1400", got)
+	}
+	if got := calls.Load(); got < 1400/64 {
+		t.Errorf("calls: %d, the rows aren't split", got)
+	}
+	for i, e := range entities {
+		want := Position{float64(i), 1}
+		if i >= 100 && i < 200 {
+			want = Position{}
+		}
+		if got := *w.GetComp[Position](e, position); got != want {
+			t.Fatalf("position of %v: %v, want: %v", e, got, want)
+		}
+	}
+}
+
+func BenchmarkCachedQuery_ParallelRun(b *testing.B) {
+	type Position struct{ x, y, z float64 }
+	type Velocity struct{ x, y, z float64 }
+
+	w := NewWorld()
+	position := w.NewComponent()
+	velocity := w.NewComponent()
+	w.NewEntities(1_000_000, MetaOf[Position](position), MetaOf[Velocity](velocity))
+	q := w.Cache(QueryAll(position, velocity))
+
+	move := func(entities []Entity, data []any) {
+		p := *data[0].(*[]Position)
+		v := *data[1].(*[]Velocity)
+		for i := range p {
+			p[i].x += v[i].x
+			p[i].y += v[i].y
+			p[i].z += v[i].z
+		}
+	}
+	b.Run("Run", func(b *testing.B) {
+		for b.Loop() {
+			q.Run(move)
+		}
+	})
+	b.Run("ParallelRun", func(b *testing.B) {
+		for b.Loop() {
+			q.ParallelRun(0, 16384, move)
+		}
+	})
+}
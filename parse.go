@@ -0,0 +1,275 @@
+package ecs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseQuery parses the query string into a Filter.
+// The Components are referred by their names given by World.SetName.
+//
+// A query is a comma-separated list of terms:
+//
+//	Position          the entity has Position
+//	!Frozen           the entity doesn't have Frozen
+//	?Sprite           the entity may or may not have Sprite
+//	(ChildOf, Scene)  the entity has the relationship (ChildOf, Scene)
+//	(ChildOf, *)      the entity has any relationship of kind ChildOf
+//
+// The target of a relationship can also be a variable like $parent,
+// which is the same as * for now.
+//
+// Like QueryAll, each required term outputs a column unless it's a tag.
+// Each optional term always outputs a column, see QueryOptional. The negative terms output nothing.
+//
+// Parsing is read-only, it doesn't modify the World, so it's safe under SyncWorld.Read.
+// The relationships which don't exist yet are looked up when the query is run,
+// and match nothing until they are created by World.Pair.
+//
+// If the query is invalid, a *QuerySyntaxError is returned.
+func (w *World) ParseQuery(query string) (Filter, error) {
+	p := queryParser{w: w, scanner: scanner{src: query}}
+	p.next()
+	var terms []Filter
+	for {
+		term, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		switch p.tok.kind {
+		case ',':
+			p.next()
+		case tokEOF:
+			return QueryAnd(terms...), nil
+		default:
+			return nil, p.errorf(p.tok.pos, "expected ',' or end of query, found %s", p.tok)
+		}
+	}
+}
+
+// A QuerySyntaxError describes an error in a query string.
+type QuerySyntaxError struct {
+	Query  string
+	Column int // The column where the error occurs, starting from 1.
+	Msg    string
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("ecs: invalid query %q at column %d: %s", e.Query, e.Column, e.Msg)
+}
+
+type queryParser struct {
+	w *World
+	scanner
+	tok token
+}
+
+func (p *queryParser) next() {
+	p.tok = p.scan()
+}
+
+func (p *queryParser) errorf(pos int, format string, args ...any) error {
+	return &QuerySyntaxError{
+		Query:  p.src,
+		Column: pos + 1,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// expect consumes a token of the kind, or returns an error.
+func (p *queryParser) expect(kind rune) (tok token, err error) {
+	if p.tok.kind != kind {
+		return p.tok, p.errorf(p.tok.pos, "expected %s, found %s", token{kind: kind}, p.tok)
+	}
+	tok = p.tok
+	p.next()
+	return
+}
+
+// term parses a term, which is a component or a relationship, optionally with an operator.
+func (p *queryParser) term() (Filter, error) {
+	op, pos := p.tok.kind, p.tok.pos
+	if op == '!' || op == '?' {
+		p.next()
+	}
+
+	var c Component
+	var target Entity
+	var isPair, wildcard bool
+	var err error
+	switch p.tok.kind {
+	case tokName:
+		c, err = p.component()
+	case '(':
+		c, target, wildcard, err = p.pair()
+		isPair = !wildcard
+	default:
+		return nil, p.errorf(p.tok.pos, "expected name or '(', found %s", p.tok)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if wildcard {
+		switch op {
+		case '!':
+			return QueryNot(QueryRel(c)), nil
+		case '?':
+			return nil, p.errorf(pos, "optional relationship with wildcard target is not supported")
+		default:
+			return QueryRel(c), nil
+		}
+	}
+	term := func(c Component) Filter {
+		switch op {
+		case '!':
+			return QueryNot(QueryAll(c))
+		case '?':
+			return QueryOptional(c)
+		default:
+			return QueryAll(c)
+		}
+	}
+	if isPair {
+		return queryPair(Pair{Rel: c, Target: target}, term), nil
+	}
+	return term(c), nil
+}
+
+// queryPair is the term of the relationship p, which is looked up each time an archetype is matched,
+// so the Pair isn't created by parsing.
+// Before the Pair is created, no archetype has it, and the term is built with an unused Component.
+func queryPair(p Pair, term func(Component) Filter) Filter {
+	missing := term(^Component(0))
+	return func(w *World, a *Archetype, out *Match) bool {
+		c, ok := w.Pairs[p]
+		if !ok {
+			return missing(w, a, out)
+		}
+		return term(c)(w, a, out)
+	}
+}
+
+// component parses a name and resolves it to a Component.
+func (p *queryParser) component() (Component, error) {
+	e, tok, err := p.entity()
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := p.w.Components[Component(e)]; !ok {
+		return 0, p.errorf(tok.pos, "%q is not a component", tok.text)
+	}
+	return Component(e), nil
+}
+
+// entity parses a name and resolves it to an Entity.
+func (p *queryParser) entity() (e Entity, tok token, err error) {
+	if tok, err = p.expect(tokName); err != nil {
+		return
+	}
+	e, ok := p.w.Lookup(tok.text)
+	if !ok {
+		err = p.errorf(tok.pos, "unknown name %q", tok.text)
+	}
+	return
+}
+
+// pair parses a relationship like (Rel, Target).
+// If the target is * or a variable, wildcard will be true.
+func (p *queryParser) pair() (rel Component, target Entity, wildcard bool, err error) {
+	if _, err = p.expect('('); err != nil {
+		return
+	}
+	if rel, err = p.component(); err != nil {
+		return
+	}
+	if _, err = p.expect(','); err != nil {
+		return
+	}
+
+	switch p.tok.kind {
+	case '*':
+		p.next()
+		wildcard = true
+	case '$':
+		p.next()
+		if _, err = p.expect(tokName); err != nil {
+			return
+		}
+		wildcard = true
+	default:
+		if target, _, err = p.entity(); err != nil {
+			return
+		}
+	}
+
+	_, err = p.expect(')')
+	return
+}
+
+const (
+	tokEOF     rune = -1
+	tokName    rune = -2
+	tokIllegal rune = -3
+)
+
+// token is a lexical token of query strings.
+// The kind is the character itself for punctuations.
+type token struct {
+	kind rune
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokName:
+		if t.text == "" {
+			return "name"
+		}
+		return fmt.Sprintf("name %q", t.text)
+	case tokIllegal:
+		return fmt.Sprintf("illegal character %q", t.text)
+	default:
+		return fmt.Sprintf("'%c'", t.kind)
+	}
+}
+
+// scanner splits query strings into tokens.
+type scanner struct {
+	src string
+	pos int
+}
+
+func (s *scanner) scan() (tok token) {
+	for s.pos < len(s.src) && strings.IndexByte(" \t\r\n", s.src[s.pos]) != -1 {
+		s.pos++
+	}
+	tok.pos = s.pos
+	if s.pos >= len(s.src) {
+		tok.kind = tokEOF
+		return
+	}
+	switch ch := s.src[s.pos]; {
+	case strings.IndexByte(",()!?$*", ch) != -1:
+		tok.kind = rune(ch)
+		s.pos++
+	case isNameChar(ch):
+		for s.pos < len(s.src) && isNameChar(s.src[s.pos]) {
+			s.pos++
+		}
+		tok.kind = tokName
+	default:
+		tok.kind = tokIllegal
+		s.pos++
+	}
+	tok.text = s.src[tok.pos:s.pos]
+	return
+}
+
+func isNameChar(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || ch == '_' || ch == '.'
+}
@@ -0,0 +1,147 @@
+package ecs
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWorld_ParseQuery(t *testing.T) {
+	w := NewWorld()
+	newComponent := func(name string) Component {
+		c := w.NewComponent()
+		w.SetName(Entity(c), name)
+		return c
+	}
+	position := newComponent("Position")
+	velocity := newComponent("Velocity")
+	frozen := newComponent("Frozen")
+	sprite := newComponent("Sprite")
+	childOf := newComponent("ChildOf")
+	scene := w.NewEntity()
+	w.SetName(scene, "Scene")
+
+	var entities [6]Entity
+	for i := range entities {
+		entities[i] = w.NewEntity()
+		w.SetComp(entities[i], position, i)
+		w.SetComp(entities[i], velocity, float64(i))
+	}
+	w.AddComp(entities[1], frozen)
+	w.SetComp(entities[2], sprite, "sprite")
+	w.AddComp(entities[3], w.Pair(childOf, scene))
+	w.AddComp(entities[4], w.Pair(childOf, entities[0]))
+	w.SetComp(entities[4], sprite, "sprite")
+
+	// id:       [0 1 2 3 4 5]
+	// Frozen:   [  x        ]
+	// Sprite:   [    x   x  ]
+	// ChildOf:  [      S 0  ]
+
+	for _, test := range []struct {
+		query   string
+		want    []int
+		sprites int
+	}{
+		{query: "Position, Velocity", want: []int{0, 1, 2, 3, 4, 5}},
+		{query: "Position, Velocity, !Frozen, ?Sprite", want: []int{0, 2, 3, 4, 5}, sprites: 2},
+		{query: "Position,(ChildOf, $parent)", want: []int{3, 4}},
+		{query: " Position , ( ChildOf , * ) , ?Sprite ", want: []int{3, 4}, sprites: 1},
+		{query: "Position, (ChildOf, Scene)", want: []int{3}},
+		{query: "Position, !(ChildOf, *)", want: []int{0, 1, 2, 5}},
+	} {
+		f, err := w.ParseQuery(test.query)
+		if err != nil {
+			t.Errorf("parse %q: %v", test.query, err)
+			continue
+		}
+		var result []int
+		var sprites int
+		w.Query(f, func(entities []Entity, data []any) {
+			result = append(result, *data[0].(*[]int)...)
+			if s, ok := data[len(data)-1].(*[]string); ok && s != nil {
+				sprites += len(*s)
+			}
+		})
+		sort.Ints(result)
+		if !reflect.DeepEqual(result, test.want) {
+			t.Errorf("query %q get: %v, want: %v", test.query, result, test.want)
+		}
+		if sprites != test.sprites {
+			t.Errorf("query %q get %d sprites, want: %d", test.query, sprites, test.sprites)
+		}
+	}
+}
+
+func TestWorld_ParseQuery_missingPair(t *testing.T) {
+	w := NewWorld()
+	position := w.NewComponent()
+	w.SetName(Entity(position), "Position")
+	childOf := w.NewComponent()
+	w.SetName(Entity(childOf), "ChildOf")
+	scene := w.NewEntity()
+	w.SetName(scene, "Scene")
+	e := w.NewEntity()
+	w.SetComp(e, position, 1)
+
+	count := len(w.Entities)
+	has, err := w.ParseQuery("Position, (ChildOf, Scene)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	not, err := w.ParseQuery("Position, !(ChildOf, Scene)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Entities) != count {
+		t.Fatalf("parsing creates %d entities", len(w.Entities)-count)
+	}
+	if _, ok := w.Pairs[Pair{Rel: childOf, Target: scene}]; ok {
+		t.Fatal("parsing creates the Pair")
+	}
+
+	qHas, qNot := w.Cache(has), w.Cache(not)
+	defer qHas.Close()
+	defer qNot.Close()
+	if qHas.Count() != 0 || qNot.Count() != 1 {
+		t.Errorf("before the Pair is created, get: %d, %d, want: 0, 1", qHas.Count(), qNot.Count())
+	}
+	w.AddComp(e, w.Pair(childOf, scene))
+	if qHas.Count() != 1 || qNot.Count() != 0 {
+		t.Errorf("after the Pair is created, get: %d, %d, want: 1, 0", qHas.Count(), qNot.Count())
+	}
+}
+
+func TestWorld_ParseQuery_error(t *testing.T) {
+	w := NewWorld()
+	w.SetName(Entity(w.NewComponent()), "Position")
+	w.SetName(Entity(w.NewComponent()), "ChildOf")
+	w.SetName(w.NewEntity(), "Bob")
+
+	for _, test := range []struct {
+		query  string
+		column int
+	}{
+		{query: "", column: 1},
+		{query: "Position,", column: 10},
+		{query: "Position, Velocity", column: 11},
+		{query: "Position, Bob", column: 11},
+		{query: "Position Bob", column: 10},
+		{query: "Position, (ChildOf Bob)", column: 20},
+		{query: "Position, (ChildOf, $)", column: 22},
+		{query: "Position, (ChildOf, Bob", column: 24},
+		{query: "Position, ?(ChildOf, *)", column: 11},
+		{query: "Position, #", column: 11},
+	} {
+		_, err := w.ParseQuery(test.query)
+		var syntaxErr *QuerySyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("parse %q: get error %v, want a QuerySyntaxError", test.query, err)
+			continue
+		}
+		if syntaxErr.Column != test.column {
+			t.Errorf("parse %q: get error at column %d, want: %d (%v)", test.query, syntaxErr.Column, test.column, err)
+		}
+	}
+}
@@ -1,15 +1,48 @@
 package ecs
 
 import (
+	"context"
 	"iter"
+	"runtime/pprof"
+	"runtime/trace"
+	"slices"
 	"weak"
 )
 
 // Filter determines whether an archetype is of interest and which specific columns it contains.
-type Filter func(*World, *Archetype, *[]int) bool
+type Filter func(*World, *Archetype, *Match) bool
 
+// Match is the output of a Filter, describing how a matched archetype is queried.
+type Match struct {
+	// The Storage indexes of the data passed to the handler.
+	// -1 means the archetype doesn't contain the data, and nil will be passed.
+	Columns []int
+
+	// The indexes in the archetype's Types of the components required to be enabled.
+	// A row is hidden from the query if any of them is disabled by World.DisableComp.
+	Toggles []int
+
+	// The terms whose data is read from other entities rather than the rows.
+	// Their slots in Columns are -1.
+	Sources []Source
+
+	// If true, the disabled entities are visible to the query.
+	Disabled bool
+}
+
+// A Source is a term of a Match whose data is read from the Component Comp of the Entity.
+// A pointer to the data, of type *T, is passed to the handler at the Slot,
+// or nil if the Entity doesn't have the data when the query is run.
+type Source struct {
+	Slot   int
+	Entity Entity
+	Comp   Component
+}
+
+// QueryAll matches the entities having all the Components.
+// The entities whose any of the Components is disabled are excluded.
 func QueryAll(comps ...Component) Filter {
-	return func(w *World, a *Archetype, out *[]int) bool {
+	return func(w *World, a *Archetype, out *Match) bool {
 		for _, c := range comps {
 			col, ok := w.Components[c][a]
 			if !ok {
@@ -17,70 +50,156 @@ func QueryAll(comps ...Component) Filter {
 			}
 			// Empty components (tags) are excluded from the output.
 			if col != -1 {
-				*out = append(*out, col)
+				out.Columns = append(out.Columns, col)
+				out.Toggles = append(out.Toggles, col)
+			} else {
+				out.Toggles = append(out.Toggles, a.index(c))
 			}
 		}
 		return true
 	}
 }
 
+// QueryAny matches the entities having any of the Components.
+// The disabled Components are not checked, their data will be passed as usual.
 func QueryAny(comps ...Component) Filter {
-	return func(w *World, a *Archetype, out *[]int) (pass bool) {
+	return func(w *World, a *Archetype, out *Match) (pass bool) {
 		for _, c := range comps {
 			if col, ok := w.Components[c][a]; ok {
 				// Empty components (tags) are excluded from the output.
 				if col != -1 {
-					*out = append(*out, col)
+					out.Columns = append(out.Columns, col)
 				}
 				pass = true
 			} else {
-				*out = append(*out, -1)
+				out.Columns = append(out.Columns, -1)
 			}
 		}
 		return
 	}
 }
 
+// QueryNot matches the entities not matched by the filter f.
+// It doesn't output any columns.
+func QueryNot(f Filter) Filter {
+	return func(w *World, a *Archetype, out *Match) bool {
+		var m Match
+		return !f(w, a, &m)
+	}
+}
+
+// QueryOptional matches all entities, and outputs the columns of the Components if they have them.
+// Unlike QueryAll and QueryAny, a column is always output for each of the Components,
+// which is nil if the Component is missing or is a tag.
+func QueryOptional(comps ...Component) Filter {
+	return func(w *World, a *Archetype, out *Match) bool {
+		for _, c := range comps {
+			if col, ok := w.Components[c][a]; ok {
+				out.Columns = append(out.Columns, col)
+			} else {
+				out.Columns = append(out.Columns, -1)
+			}
+		}
+		return true
+	}
+}
+
+// QueryRel matches the entities having any relationship of kind rel, whatever the target is.
+// Like QueryAll, the column of the first matched relationship is output unless it's a tag,
+// and the entities whose relationship is disabled are excluded.
+func QueryRel(rel Component) Filter {
+	return func(w *World, a *Archetype, out *Match) bool {
+		for i, t := range a.Types {
+			if p, ok := w.pairOf[t.Component]; ok && p.Rel == rel {
+				if a.Comps[i] != nil {
+					out.Columns = append(out.Columns, i)
+				}
+				out.Toggles = append(out.Toggles, i)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// QueryFrom matches all entities, and outputs the data of the Component c of the Entity src.
+// Unlike the other terms, a pointer *T to the single value is output rather than a column,
+// which is useful for the singletons like the camera.
+func QueryFrom(c Component, src Entity) Filter {
+	return func(w *World, a *Archetype, out *Match) bool {
+		out.Sources = append(out.Sources, Source{Slot: len(out.Columns), Entity: src, Comp: c})
+		out.Columns = append(out.Columns, -1)
+		return true
+	}
+}
+
+// QueryUp matches the entities having a relationship of kind rel,
+// and outputs the data of the Component c of the relationship's target, such as the Transform of the parent.
+// Like QueryFrom, a pointer *T to the single value is output rather than a column.
+// The entities in an archetype have the same targets, so they get the same value.
+func QueryUp(c Component, rel Component) Filter {
+	return func(w *World, a *Archetype, out *Match) bool {
+		for _, t := range a.Types {
+			if p, ok := w.pairOf[t.Component]; ok && p.Rel == rel {
+				out.Sources = append(out.Sources, Source{Slot: len(out.Columns), Entity: p.Target, Comp: c})
+				out.Columns = append(out.Columns, -1)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// QueryAnd matches the entities matched by all the filters.
+// The columns output by the filters are concatenated in order.
+func QueryAnd(filters ...Filter) Filter {
+	return func(w *World, a *Archetype, out *Match) bool {
+		for _, f := range filters {
+			if !f(w, a, out) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
 func (w *World) Query(f Filter, h func(entities []Entity, data []any)) {
-	var columns []int
+	var m Match
 	var data []any
 	for _, a := range w.Archetypes {
-		columns = columns[:0]
-		if !f(w, a, &columns) {
+		m.reset()
+		if !f(w, a, &m) {
 			continue
 		}
-		data = data[:0]
-		for _, col := range columns {
-			if col != -1 {
-				data = append(data, a.Comps[col].toSlice())
-			} else {
-				data = append(data, nil)
-			}
-		}
-		h(a.entities, data)
+		data = m.run(w, a, data, h)
 	}
 }
 
 func (w *World) Iter(f Filter) iter.Seq2[Entity, []any] {
 	return func(yield func(Entity, []any) bool) {
-		var columns []int
+		var m Match
 		var data []any
 		for _, a := range w.Archetypes {
-			columns = columns[:0]
-			if !f(w, a, &columns) {
+			m.reset()
+			if !f(w, a, &m) {
 				continue
 			}
-			if totalCol := len(columns); len(data) != totalCol {
+			m.touchSources(w)
+			if totalCol := len(m.Columns); len(data) != totalCol {
 				data = make([]any, totalCol)
 			}
 			for i, entity := range a.entities {
-				for j, col := range columns {
+				if !m.visible(a, i) {
+					continue
+				}
+				for j, col := range m.Columns {
 					if col != -1 {
 						data[j] = a.Comps[col].Get(i)
 					} else {
 						data[j] = nil
 					}
 				}
+				m.resolve(w, data)
 				if !yield(entity, data) {
 					return
 				}
@@ -89,68 +208,131 @@ func (w *World) Iter(f Filter) iter.Seq2[Entity, []any] {
 	}
 }
 
-func (w *World) Cache(f Filter) (q *CachedQuery) {
-	var columns [][]int
-	var tables []*Archetype
-
-	var out []int
-	for _, a := range w.Archetypes {
-		out = make([]int, 0, len(out))
-		if f(w, a, &out) {
-			columns = append(columns, out)
-			tables = append(tables, a)
-		}
+// Cache creates a CachedQuery of the filter.
+// The options configure how the matched archetypes are organized.
+func (w *World) Cache(f Filter, opts ...CacheOption) (q *CachedQuery) {
+	q = &CachedQuery{world: w, filter: f, index: make(map[*Archetype]int)}
+	for _, opt := range opts {
+		opt(q)
 	}
-
-	q = &CachedQuery{
-		filter:  f,
-		tables:  tables,
-		columns: columns,
-		data:    nil,
+	for _, a := range w.Archetypes {
+		q.update(w, a)
 	}
 	w.Queries.append(weak.Make(q))
 	return q
 }
 
+// CachedQueries returns the queries created by World.Cache, which are neither closed nor garbage collected.
+// It's useful for debugging.
+func (w *World) CachedQueries() (queries []*CachedQuery) {
+	for _, p := range w.Queries {
+		if q := p.Value(); q != nil {
+			queries = append(queries, q)
+		}
+	}
+	return
+}
+
+// CacheOption configures World.Cache.
+type CacheOption func(*CachedQuery)
+
 // CachedQuery is a cached filter.
 type CachedQuery struct {
+	world   *World
 	filter  Filter
 	tables  []*Archetype // All archetypes in the world that match the filter.
-	columns [][]int      // For each archetype, the storage indexes for its component data.
+	matches []Match      // For each archetype, what the filter found in it.
+
+	// The tables are partitioned into the active list tables[:active], which are the non-empty archetypes,
+	// and the inactive list tables[active:]. Only the active ones are iterated.
+	// They are moved between the lists when the archetypes become empty or non-empty.
+	active int
+	index  map[*Archetype]int // The index of each archetype in tables.
+
+	// The function to get the group key of an archetype, and the archetypes of each group.
+	groupBy func(w *World, t Types) (key uint64, ok bool)
+	groups  map[uint64][]*Archetype
+
+	// If cascade is set, the tables are ordered by their depth in the hierarchy of the relationship.
+	cascade *Component
+
+	// The name given by QueryName, for profiling.
+	name string
 
 	// Cached arguments for the callback, to avoid allocating memory every time Run is called.
 	data []any
 }
 
+// QueryName names the CachedQuery. The named queries are profiled by Run,
+// see CachedQuery.Run.
+func QueryName(name string) CacheOption {
+	return func(q *CachedQuery) {
+		q.name = name
+	}
+}
+
+// Name returns the name given by QueryName.
+func (q *CachedQuery) Name() string {
+	return q.name
+}
+
+// Run calls the handler with the matched entities and their data, once for each non-empty archetype,
+// or for each span of the visible rows if some of the rows are hidden.
+//
+// If the query is named by QueryName, it's run in a runtime/trace region of its name.
+// Use RunContext to also set the pprof label of the query.
 func (q *CachedQuery) Run(h func(entities []Entity, data []any)) {
+	if q.name == "" {
+		q.run(h)
+		return
+	}
+	trace.WithRegion(context.Background(), q.name, func() { q.run(h) })
+}
+
+// RunContext is like Run, but if the query is named by QueryName,
+// it's also run with the pprof label "ecs.query" of its name added to the labels of ctx,
+// so it can be told apart in the CPU profiles.
+// As pprof.Do, the labels of the goroutine are set back to the labels of ctx when it returns,
+// so ctx should carry the labels of the caller.
+func (q *CachedQuery) RunContext(ctx context.Context, h func(entities []Entity, data []any)) {
+	if q.name == "" {
+		q.run(h)
+		return
+	}
+	pprof.Do(ctx, pprof.Labels("ecs.query", q.name), func(ctx context.Context) {
+		trace.WithRegion(ctx, q.name, func() { q.run(h) })
+	})
+}
+
+func (q *CachedQuery) run(h func(entities []Entity, data []any)) {
+	q.sortByDepth()
 	data := q.data[:0]
-	for i, a := range q.tables {
-		data = data[:0]
-		for _, col := range q.columns[i] {
-			if col != -1 {
-				data = append(data, a.Comps[col].toSlice())
-			} else {
-				data = append(data, nil)
-			}
-		}
-		h(a.entities, data)
+	for i, a := range q.tables[:q.active] {
+		data = q.matches[i].run(q.world, a, data, h)
 	}
 	clear(data)
 	q.data = data
 }
 
 func (q *CachedQuery) Iter(yield func(entity Entity, data []any) bool) {
+	q.sortByDepth()
 	data := q.data[:0]
-	for j, a := range q.tables {
+	for j, a := range q.tables[:q.active] {
+		m := &q.matches[j]
+		m.touchSources(q.world)
 		for i, entity := range a.entities {
+			if !m.visible(a, i) {
+				continue
+			}
 			data = data[:0]
-			for _, col := range q.columns[j] {
+			for _, col := range m.Columns {
 				if col != -1 {
 					data = append(data, a.Comps[col].Get(i))
 				} else {
 					data = append(data, nil)
 				}
 			}
+			m.resolve(q.world, data)
 			if !yield(entity, data) {
 				return
 			}
@@ -160,15 +342,261 @@ func (q *CachedQuery) Iter(yield func(entity Entity, data []any) bool) {
 	q.data = data
 }
 
+// Close unregisters the CachedQuery from the World immediately.
+// After closing, the query is no longer updated and matches nothing.
+//
+// The queries not closed are unregistered after they are garbage collected.
+func (q *CachedQuery) Close() {
+	self := weak.Make(q)
+	for _, a := range q.tables {
+		if i := slices.Index(a.queries, self); i != -1 {
+			a.queries.swapDelete(i)
+		}
+	}
+	if i := slices.Index(q.world.Queries, self); i != -1 {
+		q.world.Queries.swapDelete(i)
+	}
+	q.tables, q.matches, q.active = nil, nil, 0
+	clear(q.index)
+	clear(q.groups)
+}
+
+// Count returns the number of entities matched by the query.
+// It's counted by the number of rows in the matched archetypes, without iterating the data.
+func (q *CachedQuery) Count() (n int) {
+	for i, a := range q.tables[:q.active] {
+		m := &q.matches[i]
+		if !m.masked(a) {
+			n += len(a.entities)
+			continue
+		}
+		for start, end := range m.spans(a) {
+			n += end - start
+		}
+	}
+	return
+}
+
+// IsEmpty reports whether no entity is matched by the query.
+func (q *CachedQuery) IsEmpty() bool {
+	_, ok := q.First()
+	return !ok
+}
+
+// First returns the first Entity matched by the query.
+// If no entity is matched, ok will be false.
+func (q *CachedQuery) First() (e Entity, ok bool) {
+	for e := range q.Entities() {
+		return e, true
+	}
+	return 0, false
+}
+
+// Entities returns an iterator over the entities matched by the query, without their data.
+func (q *CachedQuery) Entities() iter.Seq[Entity] {
+	return func(yield func(Entity) bool) {
+		q.sortByDepth()
+		for i, a := range q.tables[:q.active] {
+			m := &q.matches[i]
+			if !m.masked(a) {
+				for _, e := range a.entities {
+					if !yield(e) {
+						return
+					}
+				}
+				continue
+			}
+			for start, end := range m.spans(a) {
+				for _, e := range a.entities[start:end] {
+					if !yield(e) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
 func (q *CachedQuery) update(w *World, a *Archetype) {
-	var numOfCol int
-	if len(q.columns) > 0 {
-		numOfCol = len(q.columns[0])
+	var numOfCol, numOfToggles int
+	if len(q.matches) > 0 {
+		numOfCol = len(q.matches[0].Columns)
+		numOfToggles = len(q.matches[0].Toggles)
 	}
 
-	out := make([]int, 0, numOfCol)
+	out := Match{
+		Columns: make([]int, 0, numOfCol),
+		Toggles: make([]int, 0, numOfToggles),
+	}
 	if q.filter(w, a, &out) {
-		q.columns = append(q.columns, out)
+		q.matches = append(q.matches, out)
 		q.tables = append(q.tables, a)
+		q.index[a] = len(q.tables) - 1
+		a.queries.append(weak.Make(q))
+		if len(a.entities) > 0 {
+			q.activate(a, true)
+		}
+		if q.groupBy != nil {
+			if key, ok := q.groupBy(w, a.Types); ok {
+				q.groups[key] = append(q.groups[key], a)
+			}
+		}
+	}
+}
+
+// activate moves the archetype a to the active list if active is true, otherwise to the inactive list.
+func (q *CachedQuery) activate(a *Archetype, active bool) {
+	i := q.index[a]
+	if active && i >= q.active {
+		q.swap(i, q.active)
+		q.active++
+	} else if !active && i < q.active {
+		q.active--
+		q.swap(i, q.active)
+	}
+}
+
+// swap swaps the i-th and the j-th tables.
+func (q *CachedQuery) swap(i, j int) {
+	q.tables[i], q.tables[j] = q.tables[j], q.tables[i]
+	q.matches[i], q.matches[j] = q.matches[j], q.matches[i]
+	q.index[q.tables[i]], q.index[q.tables[j]] = i, j
+}
+
+// remove removes the archetype a, which must be empty, from the tables.
+func (q *CachedQuery) remove(a *Archetype) {
+	i, ok := q.index[a]
+	if !ok {
+		return
+	}
+	// The empty archetypes are in the inactive list, so is the last one.
+	last := len(q.tables) - 1
+	q.swap(i, last)
+	q.tables[last] = nil
+	q.tables, q.matches = q.tables[:last], q.matches[:last]
+	delete(q.index, a)
+	for key, group := range q.groups {
+		q.groups[key] = slices.DeleteFunc(group, func(b *Archetype) bool { return b == a })
+	}
+}
+
+// reset clears the Match so that it can be reused for another archetype.
+func (m *Match) reset() {
+	m.Columns = m.Columns[:0]
+	m.Toggles = m.Toggles[:0]
+	m.Sources = m.Sources[:0]
+	m.Disabled = false
+}
+
+// visible reports whether the row of archetype a can be seen by the query.
+func (m *Match) visible(a *Archetype, row int) bool {
+	if !m.Disabled && a.disabled.has(row) {
+		return false
+	}
+	for _, i := range m.Toggles {
+		if a.toggles[i].has(row) {
+			return false
+		}
+	}
+	return true
+}
+
+// masked reports whether some rows of archetype a might be hidden from the query.
+func (m *Match) masked(a *Archetype) bool {
+	if !m.Disabled && a.disabled.count > 0 {
+		return true
+	}
+	for _, i := range m.Toggles {
+		if a.toggles[i].count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// spans iterates over the ranges [start, end) of the consecutive rows visible to the query.
+func (m *Match) spans(a *Archetype) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		start := -1
+		for i := range a.entities {
+			switch visible := m.visible(a, i); {
+			case visible && start == -1:
+				start = i
+			case !visible && start != -1:
+				if !yield(start, i) {
+					return
+				}
+				start = -1
+			}
+		}
+		if start != -1 {
+			yield(start, len(a.entities))
+		}
+	}
+}
+
+// run calls the handler with the data of archetype a.
+// If some rows are hidden from the query, the handler is called once for each span of visible rows.
+// The data is the buffer for the handler's arguments, and will be returned for reuse.
+func (m *Match) run(w *World, a *Archetype, data []any, h func(entities []Entity, data []any)) []any {
+	w.touch(a)
+	m.touchSources(w)
+	if !m.masked(a) {
+		data = data[:0]
+		for _, col := range m.Columns {
+			if col != -1 {
+				data = append(data, a.Comps[col].toSlice())
+			} else {
+				data = append(data, nil)
+			}
+		}
+		m.resolve(w, data)
+		h(a.entities, data)
+		return data
+	}
+	for start, end := range m.spans(a) {
+		data = m.appendSlices(w, data[:0], a, start, end)
+		h(a.entities[start:end], data)
+	}
+	return data
+}
+
+// appendSlices appends the data of the rows [start, end) of archetype a to data, and returns the extended slice.
+func (m *Match) appendSlices(w *World, data []any, a *Archetype, start, end int) []any {
+	n := len(data)
+	for _, col := range m.Columns {
+		if col != -1 {
+			data = append(data, a.Comps[col].slice(start, end))
+		} else {
+			data = append(data, nil)
+		}
+	}
+	m.resolve(w, data[n:])
+	return data
+}
+
+// touchSources tells the transactions that the data of the Sources is handed out, see World.touch.
+func (m *Match) touchSources(w *World) {
+	if w.tx == nil {
+		return
+	}
+	for _, s := range m.Sources {
+		if rec, ok := w.Entities[s.Entity]; ok {
+			w.touch(rec.AT)
+		}
+	}
+}
+
+// resolve puts the data of the Sources into their slots of data.
+func (m *Match) resolve(w *World, data []any) {
+	for _, s := range m.Sources {
+		data[s.Slot] = nil
+		rec, ok := w.Entities[s.Entity]
+		if !ok {
+			continue
+		}
+		if col, ok := w.Components[s.Comp][rec.AT]; ok && col != -1 {
+			data[s.Slot] = rec.AT.Comps[col].ptr(rec.Row)
+		}
 	}
 }
@@ -216,3 +216,142 @@ func BenchmarkFilter_All(b *testing.B) {
 		b.ReportMetric(float64(b.Elapsed().Nanoseconds()/tableMatched), "ns/table")
 	})
 }
+
+func TestCachedQuery_Count(t *testing.T) {
+	w := NewWorld()
+	enemy := w.NewComponent()
+	boss := w.NewComponent()
+
+	q := w.Cache(QueryAll(enemy))
+	if q.Count() != 0 || !q.IsEmpty() {
+		t.Errorf("the query of no entities isn't empty")
+	}
+	if _, ok := q.First(); ok {
+		t.Errorf("First returns an entity of an empty query")
+	}
+
+	var entities [10]Entity
+	for i := range entities {
+		entities[i] = w.NewEntity()
+		w.AddComp(entities[i], enemy)
+	}
+	w.AddComp(entities[9], boss)
+	w.Disable(entities[0])
+	w.Disable(entities[1])
+
+	if got := q.Count(); got != 8 {
+		t.Errorf("Count get: %v, want: 8", got)
+	}
+	if q.IsEmpty() {
+		t.Errorf("IsEmpty get: true, want: false")
+	}
+	if e, ok := q.First(); !ok || e == entities[0] || e == entities[1] {
+		t.Errorf("First get: %v, %v", e, ok)
+	}
+	var result []Entity
+	for e := range q.Entities() {
+		result = append(result, e)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	if !reflect.DeepEqual(result, entities[2:]) {
+		t.Errorf("Entities get: %v, want: %v", result, entities[2:])
+	}
+
+	// Empty tables are skipped
+	for _, e := range entities[2:] {
+		w.DelEntity(e)
+	}
+	if q.Count() != 0 || !q.IsEmpty() {
+		t.Errorf("the query of disabled entities isn't empty")
+	}
+	w.Enable(entities[1])
+	if e, ok := q.First(); !ok || e != entities[1] {
+		t.Errorf("First get: %v, %v, want: %v", e, ok, entities[1])
+	}
+}
+
+func TestCachedQuery_active(t *testing.T) {
+	w := NewWorld()
+	enemy := w.NewComponent()
+	q := w.Cache(QueryAll(enemy))
+
+	// Churn the entities through many archetypes, leaving them empty.
+	tags := make([]Component, 100)
+	var entities []Entity
+	for i := range tags {
+		tags[i] = w.NewComponent()
+		e := w.NewEntity()
+		w.AddComp(e, enemy)
+		w.AddComp(e, tags[i])
+		entities = append(entities, e)
+	}
+	for i, e := range entities {
+		if i%10 != 0 {
+			w.DelEntity(e)
+		}
+	}
+
+	var calls, count int
+	q.Run(func(entities []Entity, data []any) {
+		calls++
+		count += len(entities)
+	})
+	if calls != 10 || count != 10 {
+		t.Errorf("Run get: %d calls of %d entities, want: 10 calls of 10 entities", calls, count)
+	}
+
+	// Becomes active again
+	w.AddComp(w.NewEntity(), enemy)
+	calls, count = 0, 0
+	q.Run(func(entities []Entity, data []any) {
+		calls++
+		count += len(entities)
+	})
+	if calls != 11 || count != 11 {
+		t.Errorf("Run get: %d calls of %d entities, want: 11 calls of 11 entities", calls, count)
+	}
+	for i, a := range q.tables {
+		if q.index[a] != i || (i < q.active) != (len(a.entities) > 0) {
+			t.Fatalf("the table %d is in the wrong list", i)
+		}
+	}
+}
+
+func TestCachedQuery_Close(t *testing.T) {
+	w := NewWorld()
+	c1 := w.NewComponent()
+	c2 := w.NewComponent()
+
+	q1 := w.Cache(QueryAll(c1))
+	q2 := w.Cache(QueryAll(c2))
+	e := w.NewEntity()
+	w.AddComp(e, c1)
+	if got := w.CachedQueries(); len(got) != 2 {
+		t.Fatalf("CachedQueries get: %v, want 2 queries", got)
+	}
+
+	q1.Close()
+	if got := w.CachedQueries(); len(got) != 1 || got[0] != q2 {
+		t.Errorf("CachedQueries get: %v, want: [%p]", got, q2)
+	}
+	for _, a := range w.Archetypes {
+		for _, p := range a.queries {
+			if p.Value() == q1 {
+				t.Errorf("the closed query is still referenced by an archetype")
+			}
+		}
+	}
+	if !q1.IsEmpty() {
+		t.Errorf("the closed query isn't empty")
+	}
+
+	// Not updated after closing
+	w.AddComp(e, c2)
+	if !q1.IsEmpty() {
+		t.Errorf("the closed query is updated")
+	}
+	if e2, ok := q2.First(); !ok || e2 != e {
+		t.Errorf("First get: %v, %v, want: %v", e2, ok, e)
+	}
+	q1.Close() // Closing twice does nothing
+}
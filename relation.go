@@ -0,0 +1,56 @@
+package ecs
+
+// A Pair is a relationship from an entity to the Target, such as (ChildOf, parent) or (Likes, apples).
+// The Rel is the Component representing the kind of the relationship.
+//
+// Each Pair is represented by a Component, which can be added to entities like any other Components.
+// Relationships are part of the entities' archetypes,
+// so the entities with the same relationships are stored together.
+type Pair struct {
+	Rel    Component
+	Target Entity
+}
+
+// Pair returns the Component representing the relationship rel to the target.
+// The Component is created the first time the Pair is used.
+func (w *World) Pair(rel Component, target Entity) Component {
+	p := Pair{Rel: rel, Target: target}
+	c, ok := w.Pairs[p]
+	if !ok {
+		c = w.NewComponent()
+		w.Pairs[p] = c
+		w.pairOf[c] = p
+	}
+	return c
+}
+
+// PairOf reports which relationship the Component represents.
+// If the Component is not created by World.Pair, ok will be false.
+func (w *World) PairOf(c Component) (p Pair, ok bool) {
+	p, ok = w.pairOf[c]
+	return
+}
+
+// Targets returns the targets of the Entity's relationships of kind rel.
+func (w *World) Targets(e Entity, rel Component) (targets []Entity) {
+	rec := w.Entities[e]
+	for _, t := range rec.AT.Types {
+		if p, ok := w.pairOf[t.Component]; ok && p.Rel == rel {
+			targets = append(targets, p.Target)
+		}
+	}
+	return
+}
+
+// Sources returns the entities which have the relationship rel to the target.
+// For example, the children of a parent are the sources of (ChildOf, parent).
+func (w *World) Sources(rel Component, target Entity) (sources []Entity) {
+	c, ok := w.Pairs[Pair{Rel: rel, Target: target}]
+	if !ok {
+		return nil
+	}
+	for a := range w.Components[c] {
+		sources = append(sources, a.entities...)
+	}
+	return
+}
@@ -0,0 +1,308 @@
+package ecs
+
+// A Rule is a query with variables, which matches multiple related entities at once.
+// For example, the rule
+//
+//	SpaceShip($this), DockedTo($this, $planet), Planet($planet)
+//
+// finds every pair of a space ship and the planet it's docked to.
+// A Rule is created by World.ParseRule.
+type Rule struct {
+	w     *World
+	terms []ruleTerm
+	vars  []string
+}
+
+// ruleTerm is a term of a Rule.
+// For one-argument terms like Comp(src), the src has the Component.
+// For two-argument terms like Rel(src, target), the src has the relationship (Rel, target).
+type ruleTerm struct {
+	comp   Component
+	src    ruleRef
+	target *ruleRef
+	not    bool
+}
+
+// ruleRef is an argument of a term, which is either a variable or a fixed Entity.
+type ruleRef struct {
+	v int // The index of the variable, or -1 for the fixed Entity.
+	e Entity
+}
+
+// ParseRule parses a Rule, which is a comma-separated list of terms:
+//
+//	Planet($planet)           $planet has the Component Planet
+//	DockedTo($this, $planet)  $this has the relationship (DockedTo, $planet)
+//	DockedTo($this, Earth)    $this has the relationship (DockedTo, Earth)
+//	!Destroyed($planet)       $planet doesn't have the Component Destroyed
+//
+// The arguments are variables starting with $, or the names of entities given by World.SetName.
+// The variables in negative terms must also appear in the positive terms.
+//
+// If the rule is invalid, a *QuerySyntaxError is returned.
+func (w *World) ParseRule(rule string) (*Rule, error) {
+	p := ruleParser{
+		queryParser: queryParser{w: w, scanner: scanner{src: rule}},
+		rule:        &Rule{w: w},
+		vars:        make(map[string]int),
+	}
+	p.next()
+	var negatives []ruleTerm
+	var negativePos []int
+	for {
+		pos := p.tok.pos
+		t, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		// The negative terms are checked at last, when their variables are bound.
+		if t.not {
+			negatives = append(negatives, t)
+			negativePos = append(negativePos, pos)
+		} else {
+			p.rule.terms = append(p.rule.terms, t)
+		}
+		switch p.tok.kind {
+		case ',':
+			p.next()
+			continue
+		case tokEOF:
+		default:
+			return nil, p.errorf(p.tok.pos, "expected ',' or end of query, found %s", p.tok)
+		}
+		break
+	}
+
+	// The fixed entities are always bound, only the variables are checked.
+	positive := map[int]bool{-1: true}
+	for _, t := range p.rule.terms {
+		positive[t.src.v] = true
+		if t.target != nil {
+			positive[t.target.v] = true
+		}
+	}
+	for i, t := range negatives {
+		if !positive[t.src.v] || t.target != nil && !positive[t.target.v] {
+			return nil, p.errorf(negativePos[i], "variables in negative terms must appear in positive terms")
+		}
+	}
+	p.rule.terms = append(p.rule.terms, negatives...)
+	return p.rule, nil
+}
+
+type ruleParser struct {
+	queryParser
+	rule *Rule
+	vars map[string]int
+}
+
+func (p *ruleParser) term() (t ruleTerm, err error) {
+	if p.tok.kind == '!' {
+		t.not = true
+		p.next()
+	}
+	if t.comp, err = p.component(); err != nil {
+		return
+	}
+	if _, err = p.expect('('); err != nil {
+		return
+	}
+	if t.src, err = p.ref(); err != nil {
+		return
+	}
+	if p.tok.kind == ',' {
+		p.next()
+		var target ruleRef
+		if target, err = p.ref(); err != nil {
+			return
+		}
+		t.target = &target
+	}
+	_, err = p.expect(')')
+	return
+}
+
+// ref parses a variable or an entity name.
+func (p *ruleParser) ref() (ruleRef, error) {
+	if p.tok.kind != '$' {
+		e, _, err := p.entity()
+		return ruleRef{v: -1, e: e}, err
+	}
+	p.next()
+	tok, err := p.expect(tokName)
+	if err != nil {
+		return ruleRef{}, err
+	}
+	v, ok := p.vars[tok.text]
+	if !ok {
+		v = len(p.rule.vars)
+		p.vars[tok.text] = v
+		p.rule.vars = append(p.rule.vars, tok.text)
+	}
+	return ruleRef{v: v}, nil
+}
+
+// Vars returns the names of the variables, without the $ prefix.
+// The index of a name is the index of the variable's value in the bindings passed by Iter.
+func (r *Rule) Vars() []string {
+	return r.vars
+}
+
+// Var returns the index of the variable in the bindings passed by Iter, or -1 if it doesn't exist.
+// The name is without the $ prefix.
+func (r *Rule) Var(name string) int {
+	for i, v := range r.vars {
+		if v == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Iter iterates every valid binding of the variables.
+// The bindings are the values of the variables, indexed as Vars,
+// and the slice is reused between iterations.
+//
+// Like other queries, the disabled entities and Components are not matched.
+// The World must not be modified during the iteration.
+func (r *Rule) Iter(yield func(bindings []Entity) bool) {
+	s := ruleSolver{
+		Rule:     r,
+		bindings: make([]Entity, len(r.vars)),
+		bound:    make([]bool, len(r.vars)),
+		yield:    yield,
+	}
+	s.solve(0)
+}
+
+// ruleSolver finds the bindings by backtracking.
+type ruleSolver struct {
+	*Rule
+	bindings []Entity
+	bound    []bool
+	yield    func([]Entity) bool
+}
+
+// solve binds the variables of the i-th term and the following terms.
+// It returns false if the iteration is stopped.
+func (s *ruleSolver) solve(i int) bool {
+	if i == len(s.terms) {
+		return s.yield(s.bindings)
+	}
+	t := &s.terms[i]
+	src, srcBound := s.value(t.src)
+
+	if t.not {
+		return s.holds(t, src, s.targetValue(t)) || s.solve(i+1)
+	}
+
+	if srcBound {
+		if t.target == nil {
+			return !s.enabled(src, t.comp) || s.solve(i+1)
+		}
+		if target, ok := s.value(*t.target); ok {
+			return !s.holds(t, src, target) || s.solve(i+1)
+		}
+		// The src is a fixed Entity which might have been deleted.
+		if _, ok := s.w.Entities[src]; !ok {
+			return true
+		}
+		for _, target := range s.w.Targets(src, t.comp) {
+			if s.enabled(src, s.w.Pairs[Pair{Rel: t.comp, Target: target}]) {
+				if !s.bind(t.target.v, target, func() bool { return s.solve(i + 1) }) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	// The src is unbound, enumerate all the candidates.
+	if t.target == nil {
+		return s.each(t.comp, func(e Entity) bool {
+			return s.bind(t.src.v, e, func() bool { return s.solve(i + 1) })
+		})
+	}
+	if target, ok := s.value(*t.target); ok {
+		pair, ok := s.w.Pairs[Pair{Rel: t.comp, Target: target}]
+		return !ok || s.each(pair, func(e Entity) bool {
+			return s.bind(t.src.v, e, func() bool { return s.solve(i + 1) })
+		})
+	}
+	for pair, c := range s.w.Pairs {
+		if pair.Rel != t.comp {
+			continue
+		}
+		ok := s.each(c, func(e Entity) bool {
+			return s.bind(t.src.v, e, func() bool {
+				// The src and target might be the same variable.
+				if target, ok := s.value(*t.target); ok {
+					return target != pair.Target || s.solve(i+1)
+				}
+				return s.bind(t.target.v, pair.Target, func() bool { return s.solve(i + 1) })
+			})
+		})
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// value returns the value of the ref, and whether it's bound.
+func (s *ruleSolver) value(r ruleRef) (Entity, bool) {
+	if r.v == -1 {
+		return r.e, true
+	}
+	return s.bindings[r.v], s.bound[r.v]
+}
+
+func (s *ruleSolver) targetValue(t *ruleTerm) Entity {
+	if t.target == nil {
+		return 0
+	}
+	e, _ := s.value(*t.target)
+	return e
+}
+
+// bind binds the variable to the Entity during the call of next.
+func (s *ruleSolver) bind(v int, e Entity, next func() bool) bool {
+	if s.bound[v] {
+		return s.bindings[v] != e || next()
+	}
+	s.bindings[v], s.bound[v] = e, true
+	ok := next()
+	s.bound[v] = false
+	return ok
+}
+
+// holds reports whether the term is true for the bound src and target.
+func (s *ruleSolver) holds(t *ruleTerm, src, target Entity) bool {
+	if t.target == nil {
+		return s.enabled(src, t.comp)
+	}
+	pair, ok := s.w.Pairs[Pair{Rel: t.comp, Target: target}]
+	return ok && s.enabled(src, pair)
+}
+
+// enabled reports whether the Entity is alive and enabled, and has the Component enabled.
+func (s *ruleSolver) enabled(e Entity, c Component) bool {
+	rec, ok := s.w.Entities[e]
+	return ok && !rec.AT.disabled.has(rec.Row) && s.w.IsCompEnabled(e, c)
+}
+
+// each calls f for each enabled Entity having the Component c, until f returns false.
+func (s *ruleSolver) each(c Component, f func(e Entity) bool) bool {
+	for a := range s.w.Components[c] {
+		i := a.index(c)
+		for row, e := range a.entities {
+			if a.disabled.has(row) || a.toggles[i].has(row) {
+				continue
+			}
+			if !f(e) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package ecs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWorld_ParseRule(t *testing.T) {
+	w := NewWorld()
+	named := func(e Entity, name string) Entity {
+		w.SetName(e, name)
+		return e
+	}
+	spaceShip := Component(named(Entity(w.NewComponent()), "SpaceShip"))
+	planet := Component(named(Entity(w.NewComponent()), "Planet"))
+	dockedTo := Component(named(Entity(w.NewComponent()), "DockedTo"))
+	destroyed := Component(named(Entity(w.NewComponent()), "Destroyed"))
+
+	earth := named(w.NewEntity(), "Earth")
+	mars := named(w.NewEntity(), "Mars")
+	station := named(w.NewEntity(), "Station")
+	w.AddComp(earth, planet)
+	w.AddComp(mars, planet)
+	w.AddComp(mars, destroyed)
+
+	newShip := func(name string, dock Entity) Entity {
+		e := named(w.NewEntity(), name)
+		w.AddComp(e, spaceShip)
+		w.AddComp(e, w.Pair(dockedTo, dock))
+		return e
+	}
+	enterprise := newShip("Enterprise", earth)
+	newShip("Voyager", mars)
+	newShip("Defiant", station) // Not a planet
+	disabled := newShip("Galactica", earth)
+	w.Disable(disabled)
+
+	for _, test := range []struct {
+		rule string
+		want []string
+	}{
+		{
+			rule: "SpaceShip($this), DockedTo($this, $planet), Planet($planet)",
+			want: []string{"Enterprise Earth", "Voyager Mars"},
+		},
+		{
+			rule: "DockedTo($this, $planet), Planet($planet), SpaceShip($this)",
+			want: []string{"Enterprise Earth", "Voyager Mars"},
+		},
+		{
+			rule: "SpaceShip($this), DockedTo($this, $planet), Planet($planet), !Destroyed($planet)",
+			want: []string{"Enterprise Earth"},
+		},
+		{
+			rule: "DockedTo($this, Earth)",
+			want: []string{"Enterprise"},
+		},
+		{
+			rule: "Planet(Earth), SpaceShip($this), !DockedTo($this, Earth)",
+			want: []string{"Defiant", "Voyager"},
+		},
+		{
+			rule: "SpaceShip($this), !DockedTo($this, Earth)",
+			want: []string{"Defiant", "Voyager"},
+		},
+		{
+			rule: "Planet(Mars), Destroyed(Earth), SpaceShip($this)",
+			want: nil,
+		},
+	} {
+		r, err := w.ParseRule(test.rule)
+		if err != nil {
+			t.Errorf("parse %q: %v", test.rule, err)
+			continue
+		}
+		var result []string
+		for bindings := range r.Iter {
+			var s string
+			for i, e := range bindings {
+				if i > 0 {
+					s += " "
+				}
+				s += w.Name(e)
+			}
+			result = append(result, s)
+		}
+		sort.Strings(result)
+		if !reflect.DeepEqual(result, test.want) {
+			t.Errorf("rule %q get: %q, want: %q", test.rule, result, test.want)
+		}
+	}
+
+	r, _ := w.ParseRule("SpaceShip($this), DockedTo($this, $planet)")
+	if got := fmt.Sprint(r.Vars(), r.Var("planet"), r.Var("moon")); got != "[this planet] 1 -1" {
+		t.Errorf("variables: %s", got)
+	}
+
+	// The fixed Entity is deleted after the rule is parsed.
+	r, err := w.ParseRule("DockedTo(Enterprise, $planet)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.DelEntity(enterprise)
+	for bindings := range r.Iter {
+		t.Errorf("the deleted Entity get bindings: %v", bindings)
+	}
+}
+
+func TestWorld_ParseRule_error(t *testing.T) {
+	w := NewWorld()
+	w.SetName(Entity(w.NewComponent()), "Planet")
+	w.SetName(Entity(w.NewComponent()), "DockedTo")
+
+	for _, test := range []struct {
+		rule   string
+		column int
+	}{
+		{rule: "Planet", column: 7},
+		{rule: "Planet($this", column: 13},
+		{rule: "Planet($this), Moon($this)", column: 16},
+		{rule: "Planet($this), DockedTo($this, Mars)", column: 32},
+		{rule: "Planet($this), !DockedTo($this, $planet)", column: 16},
+	} {
+		_, err := w.ParseRule(test.rule)
+		var syntaxErr *QuerySyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("parse %q: get error %v, want a QuerySyntaxError", test.rule, err)
+			continue
+		}
+		if syntaxErr.Column != test.column {
+			t.Errorf("parse %q: get error at column %d, want: %d (%v)", test.rule, syntaxErr.Column, test.column, err)
+		}
+	}
+}
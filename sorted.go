@@ -0,0 +1,122 @@
+package ecs
+
+import "sort"
+
+// CacheSorted creates a SortedQuery, which iterates the entities matched by the filter in the order of their Component c.
+// The entities without the Component c are not matched.
+//
+// The order is maintained as a merged index across all matched archetypes,
+// and is only rebuilt when the change detection says the Component c,
+// or the rows of the matched archetypes, are changed.
+// So if the data is modified in place rather than by SetComp, call World.Modified.
+func (w *World) CacheSorted[T any](f Filter, c Component, less func(a, b *T) bool) *SortedQuery {
+	q := w.Cache(func(w *World, a *Archetype, out *Match) bool {
+		if col, ok := w.Components[c][a]; !ok || col == -1 {
+			return false
+		}
+		return f(w, a, out)
+	})
+	return &SortedQuery{
+		world: w,
+		query: q,
+		comp:  c,
+		less: func(a *Archetype, i int, b *Archetype, j int) bool {
+			return less(
+				&(*a.Comps[w.Components[c][a]].(*Table[T]))[i],
+				&(*b.Comps[w.Components[c][b]].(*Table[T]))[j],
+			)
+		},
+	}
+}
+
+// SortedQuery is a cached query which iterates the entities in order.
+// It's created by World.CacheSorted.
+type SortedQuery struct {
+	world *World
+	query *CachedQuery
+	comp  Component
+	less  func(a *Archetype, i int, b *Archetype, j int) bool
+
+	// The sorted rows, as indexes in query.tables and the rows in the tables.
+	order []sortedRow
+	// The versions of the tables when they are sorted.
+	seen []tableVersion
+}
+
+type sortedRow struct {
+	table, row int
+}
+
+type tableVersion struct {
+	a                *Archetype
+	version, changed uint64
+}
+
+// Close unregisters the SortedQuery from the World immediately, like CachedQuery.Close.
+func (q *SortedQuery) Close() {
+	q.query.Close()
+	q.order, q.seen = nil, nil
+}
+
+// Iter iterates the entities in the order.
+// The data passed to yield is the same as CachedQuery.Iter.
+func (q *SortedQuery) Iter(yield func(entity Entity, data []any) bool) {
+	if q.dirty() {
+		q.sort()
+	}
+	for i := range q.query.matches[:q.query.active] {
+		q.query.matches[i].touchSources(q.world)
+	}
+	var data []any
+	for _, r := range q.order {
+		a := q.query.tables[r.table]
+		data = data[:0]
+		for _, col := range q.query.matches[r.table].Columns {
+			if col != -1 {
+				data = append(data, a.Comps[col].Get(r.row))
+			} else {
+				data = append(data, nil)
+			}
+		}
+		q.query.matches[r.table].resolve(q.world, data)
+		if !yield(a.entities[r.row], data) {
+			return
+		}
+	}
+}
+
+// dirty reports whether the order is outdated.
+func (q *SortedQuery) dirty() bool {
+	if len(q.seen) != len(q.query.tables) {
+		return true
+	}
+	for i, a := range q.query.tables {
+		v := q.seen[i]
+		if v.a != a || v.version != a.version || v.changed != a.changed[q.world.Components[q.comp][a]] {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *SortedQuery) sort() {
+	q.order = q.order[:0]
+	q.seen = q.seen[:0]
+	for i, a := range q.query.tables {
+		m := &q.query.matches[i]
+		for row := range a.entities {
+			if m.visible(a, row) {
+				q.order = append(q.order, sortedRow{table: i, row: row})
+			}
+		}
+		q.seen = append(q.seen, tableVersion{
+			a:       a,
+			version: a.version,
+			changed: a.changed[q.world.Components[q.comp][a]],
+		})
+	}
+	sort.SliceStable(q.order, func(i, j int) bool {
+		a, b := q.order[i], q.order[j]
+		return q.less(q.query.tables[a.table], a.row, q.query.tables[b.table], b.row)
+	})
+}
@@ -0,0 +1,65 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWorld_CacheSorted(t *testing.T) {
+	type Depth struct{ z int }
+
+	w := NewWorld()
+	depth := w.NewComponent()
+	sprite := w.NewComponent()
+	hidden := w.NewComponent()
+
+	depths := []int{5, 3, 9, 1, 7, 2}
+	var entities []Entity
+	for i, d := range depths {
+		e := w.NewEntity()
+		w.SetComp(e, depth, Depth{d})
+		// Spread the entities into different archetypes
+		if i%2 == 0 {
+			w.AddComp(e, sprite)
+		}
+		entities = append(entities, e)
+	}
+	w.AddComp(w.NewEntity(), sprite) // Not matched, doesn't have Depth
+
+	sortCount := 0
+	q := w.CacheSorted(QueryAll(depth), depth, func(a, b *Depth) bool {
+		sortCount++
+		return a.z < b.z
+	})
+	judge := func(t *testing.T, want []int) {
+		t.Helper()
+		var result []int
+		for _, data := range q.Iter {
+			result = append(result, data[0].(Depth).z)
+		}
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("get: %v, want: %v", result, want)
+		}
+	}
+
+	judge(t, []int{1, 2, 3, 5, 7, 9})
+
+	// Not sorted again when nothing changes
+	sortCount = 0
+	judge(t, []int{1, 2, 3, 5, 7, 9})
+	if sortCount != 0 {
+		t.Errorf("sorted again without changes")
+	}
+
+	w.SetComp(entities[2], depth, Depth{0})
+	judge(t, []int{0, 1, 2, 3, 5, 7})
+
+	w.GetComp[Depth](entities[0], depth).z = 10
+	w.Modified(entities[0], depth)
+	judge(t, []int{0, 1, 2, 3, 7, 10})
+
+	w.Disable(entities[1])
+	w.DelEntity(entities[3])
+	w.AddComp(entities[4], hidden) // moves to a new archetype
+	judge(t, []int{0, 2, 7, 10})
+}
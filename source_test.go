@@ -0,0 +1,89 @@
+package ecs
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestQueryFrom(t *testing.T) {
+	type Camera struct{ x, y int }
+
+	w := NewWorld()
+	position := w.NewComponent()
+	camera := w.NewComponent()
+	cam := w.NewEntity()
+	w.SetComp(cam, camera, Camera{10, 20})
+	for i := range 3 {
+		w.SetComp(w.NewEntity(), position, i)
+	}
+
+	q := w.Cache(QueryAnd(QueryAll(position), QueryFrom(camera, cam)))
+	run := func() (result []int) {
+		q.Run(func(entities []Entity, data []any) {
+			positions := *data[0].(*[]int)
+			c, _ := data[1].(*Camera)
+			for _, p := range positions {
+				if c == nil {
+					result = append(result, -1)
+				} else {
+					result = append(result, p+c.x)
+				}
+			}
+		})
+		sort.Ints(result)
+		return
+	}
+	if result := run(); !reflect.DeepEqual(result, []int{10, 11, 12}) {
+		t.Errorf("get: %v, want: %v", result, []int{10, 11, 12})
+	}
+
+	// The source is resolved each time the query is run.
+	w.AddComp(cam, w.NewComponent()) // moves the source to another archetype
+	w.GetComp[Camera](cam, camera).x = 100
+	if result := run(); !reflect.DeepEqual(result, []int{100, 101, 102}) {
+		t.Errorf("get: %v, want: %v", result, []int{100, 101, 102})
+	}
+
+	w.DelComp(cam, camera)
+	if result := run(); !reflect.DeepEqual(result, []int{-1, -1, -1}) {
+		t.Errorf("get: %v, want: %v", result, []int{-1, -1, -1})
+	}
+}
+
+func TestQueryUp(t *testing.T) {
+	w := NewWorld()
+	transform := w.NewComponent()
+	childOf := w.NewComponent()
+
+	parents := []Entity{w.NewEntity(), w.NewEntity()}
+	w.SetComp(parents[0], transform, 100)
+	w.SetComp(parents[1], transform, 200)
+	for i := range 6 {
+		e := w.NewEntity()
+		w.SetComp(e, transform, i)
+		w.AddComp(e, w.Pair(childOf, parents[i%2]))
+	}
+
+	f := QueryAnd(QueryAll(transform), QueryUp(transform, childOf))
+	var result []int
+	w.Query(f, func(entities []Entity, data []any) {
+		parent := *data[1].(*int)
+		for _, v := range *data[0].(*[]int) {
+			result = append(result, parent+v)
+		}
+	})
+	sort.Ints(result)
+	if want := []int{100, 102, 104, 201, 203, 205}; !reflect.DeepEqual(result, want) {
+		t.Errorf("Query get: %v, want: %v", result, want)
+	}
+
+	result = result[:0]
+	for _, data := range w.Cache(f).Iter {
+		result = append(result, *data[1].(*int)+data[0].(int))
+	}
+	sort.Ints(result)
+	if want := []int{100, 102, 104, 201, 203, 205}; !reflect.DeepEqual(result, want) {
+		t.Errorf("Iter get: %v, want: %v", result, want)
+	}
+}
@@ -0,0 +1,120 @@
+package ecs
+
+import (
+	"cmp"
+	"expvar"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// Stats is a snapshot of the statistics of a World, returned by World.Stats.
+type Stats struct {
+	Entities        int // The number of alive entities, including the Components.
+	Components      int
+	Archetypes      int
+	EmptyArchetypes int // The number of archetypes without any entities.
+	CachedQueries   int // The number of cached queries neither closed nor garbage collected.
+
+	// The number of lookups of the target archetype by AddComp, SetComp and DelComp,
+	// which hit or miss the cached ArchetypeEdge.
+	EdgeHits, EdgeMisses uint64
+
+	// The number of the recycled ids waiting for reuse.
+	Freelist int
+
+	// The statistics of each archetype, with the most rows first.
+	Tables []ArchetypeStats
+
+	// The timing of each System, in the order they are run.
+	Systems []SystemStats
+}
+
+// SystemStats is the timing of a System.
+type SystemStats struct {
+	Name string
+	Runs uint64
+	// The time spent by all the runs, and by the last run.
+	Total, Last time.Duration
+}
+
+// ArchetypeStats is the statistics of an Archetype.
+type ArchetypeStats struct {
+	Types   []Component
+	Rows    int
+	Columns []ColumnStats // The columns of data, not including the tags.
+}
+
+// ColumnStats is the statistics of a column of an Archetype, which is a Table[T].
+type ColumnStats struct {
+	Component Component
+	Type      string // The name of T.
+	Len, Cap  int
+	// The memory allocated for the column, which is Cap * sizeof(T).
+	// The memory referenced by T, like the content of strings and slices, is not counted.
+	Bytes uintptr
+}
+
+// Stats collects the statistics of the World.
+func (w *World) Stats() (s Stats) {
+	s = Stats{
+		Entities:      len(w.Entities),
+		Components:    len(w.Components),
+		Archetypes:    len(w.Archetypes),
+		CachedQueries: len(w.CachedQueries()),
+		EdgeHits:      w.edgeHits,
+		EdgeMisses:    w.edgeMisses,
+		Freelist:      len(w.Freelist),
+		Tables:        make([]ArchetypeStats, 0, len(w.Archetypes)),
+	}
+	for _, a := range w.Archetypes {
+		if len(a.entities) == 0 {
+			s.EmptyArchetypes++
+		}
+		as := ArchetypeStats{
+			Types: make([]Component, len(a.Types)),
+			Rows:  len(a.entities),
+		}
+		for i, t := range a.Types {
+			as.Types[i] = t.Component
+			if a.Comps[i] == nil {
+				continue
+			}
+			v := reflect.ValueOf(a.Comps[i]).Elem()
+			as.Columns = append(as.Columns, ColumnStats{
+				Component: t.Component,
+				Type:      v.Type().Elem().String(),
+				Len:       v.Len(),
+				Cap:       v.Cap(),
+				Bytes:     uintptr(v.Cap()) * v.Type().Elem().Size(),
+			})
+		}
+		s.Tables = append(s.Tables, as)
+	}
+	for _, sys := range w.systems {
+		s.Systems = append(s.Systems, SystemStats{Name: sys.Name, Runs: sys.runs, Total: sys.total, Last: sys.last})
+	}
+	slices.SortFunc(s.Tables, func(a, b ArchetypeStats) int {
+		return cmp.Compare(b.Rows, a.Rows)
+	})
+	return
+}
+
+// PublishStats exports the Stats of the World as an expvar.Var of the name,
+// which is collected each time the expvar is read.
+// Like expvar.Publish, it panics if the name is already registered.
+//
+// As the expvar can be read from any goroutine, e.g. by the /debug/vars handler,
+// the World must not be modified concurrently.
+// Use SyncWorld.PublishStats for a World being modified.
+func (w *World) PublishStats(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return w.Stats() }))
+}
+
+// PublishStats is like World.PublishStats, but collects the Stats holding the read lock.
+func (s *SyncWorld) PublishStats(name string) {
+	expvar.Publish(name, expvar.Func(func() (stats any) {
+		s.Read(func(w *World) { stats = w.Stats() })
+		return
+	}))
+}
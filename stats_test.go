@@ -0,0 +1,60 @@
+package ecs
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// statsRuns numbers the runs of TestWorld_Stats,
+// as an expvar can only be published once in a process but the test may run many times.
+var statsRuns atomic.Int32
+
+func TestWorld_Stats(t *testing.T) {
+	type Position struct{ x, y float64 }
+
+	w := NewWorld()
+	position := w.NewComponent()
+	tag := w.NewComponent()
+	q := w.Cache(QueryAll(position))
+	defer q.Close()
+
+	for range 3 {
+		e := w.NewEntity()
+		w.SetComp(e, position, Position{}) // 1 miss, then 2 hits
+		w.AddComp(e, tag)                  // 1 miss, then 2 hits
+	}
+	w.DelEntity(w.NewEntity())
+
+	s := w.Stats()
+	if s.Entities != 5 || s.Components != 2 || s.Freelist != 1 || s.CachedQueries != 1 {
+		t.Errorf("Stats get: %+v", s)
+	}
+	// Zero, (position), (position, tag)
+	if s.Archetypes != 3 || s.EmptyArchetypes != 1 {
+		t.Errorf("Archetypes get: %d, %d empty, want: 3, 1 empty", s.Archetypes, s.EmptyArchetypes)
+	}
+	if s.EdgeHits != 4 || s.EdgeMisses != 2 {
+		t.Errorf("edge hits: %d, misses: %d, want: 4, 2", s.EdgeHits, s.EdgeMisses)
+	}
+
+	top := s.Tables[0]
+	if top.Rows != 3 || len(top.Types) != 2 || len(top.Columns) != 1 {
+		t.Fatalf("the first table get: %+v", top)
+	}
+	if col := top.Columns[0]; col.Component != position || col.Len != 3 || col.Bytes != uintptr(col.Cap)*16 {
+		t.Errorf("the column get: %+v", col)
+	}
+
+	name := fmt.Sprintf("TestWorld_Stats%d", statsRuns.Add(1))
+	w.PublishStats(name)
+	var published Stats
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &published); err != nil {
+		t.Fatal(err)
+	}
+	if published.Entities != 5 {
+		t.Errorf("the published Entities get: %d, want: 5", published.Entities)
+	}
+}
@@ -0,0 +1,135 @@
+package ecs
+
+import "sync"
+
+// SyncWorld guards a World for concurrent access.
+//
+// The locking granularity is the whole World.
+// Any number of goroutines can read the World at the same time by Read,
+// while Write excludes all other readers and writers.
+//
+// The changes which don't have to be seen immediately can be recorded into Stages without locking,
+// and are applied together at the next Sync, which is the sync point of the stages.
+type SyncWorld struct {
+	mu    sync.RWMutex
+	world *World
+
+	// The committed stages waiting for the next Sync.
+	stagesMu sync.Mutex
+	stages   [][]func(*World)
+}
+
+// NewSyncWorld creates a SyncWorld guarding w.
+// After that, w should only be accessed through the SyncWorld.
+func NewSyncWorld(w *World) *SyncWorld {
+	return &SyncWorld{world: w}
+}
+
+// Read calls f with the World, holding the read lock.
+//
+// The f must not modify the World, or the data of the Components.
+// Note that CachedQuery isn't safe for concurrent use,
+// so a CachedQuery can't be run by multiple readers at the same time.
+func (s *SyncWorld) Read(f func(w *World)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f(s.world)
+}
+
+// Write calls f with the World, holding the write lock.
+func (s *SyncWorld) Write(f func(w *World)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.world)
+}
+
+// Stage creates a new Stage for recording changes to the World.
+func (s *SyncWorld) Stage() *Stage {
+	return &Stage{sync: s}
+}
+
+// Sync applies the changes of all committed Stages, in the order they are committed.
+// It holds the write lock while applying.
+func (s *SyncWorld) Sync() {
+	s.stagesMu.Lock()
+	stages := s.stages
+	s.stages = nil
+	s.stagesMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, commands := range stages {
+		for _, cmd := range commands {
+			cmd(s.world)
+		}
+	}
+}
+
+// A Stage records the changes to the World, which are applied at the next SyncWorld.Sync after it's committed.
+//
+// A Stage isn't safe for concurrent use, each goroutine should have its own Stage.
+type Stage struct {
+	sync     *SyncWorld
+	commands []func(*World)
+}
+
+// Do records a command which will be called with the World at the sync point.
+func (st *Stage) Do(cmd func(w *World)) {
+	st.commands = append(st.commands, cmd)
+}
+
+// The commands below skip the entities which are deleted before the sync point,
+// for example by another Stage committed earlier.
+
+// AddComp records a World.AddComp call.
+func (st *Stage) AddComp(e Entity, c Component) {
+	st.Do(func(w *World) {
+		if alive(w, e) {
+			w.AddComp(e, c)
+		}
+	})
+}
+
+// SetComp records a World.SetComp call.
+func (st *Stage) SetComp[C any](e Entity, c Component, data C) {
+	st.Do(func(w *World) {
+		if alive(w, e) {
+			w.SetComp(e, c, data)
+		}
+	})
+}
+
+// DelComp records a World.DelComp call.
+func (st *Stage) DelComp(e Entity, c Component) {
+	st.Do(func(w *World) {
+		if alive(w, e) {
+			w.DelComp(e, c)
+		}
+	})
+}
+
+// DelEntity records a World.DelEntity call.
+func (st *Stage) DelEntity(e Entity) {
+	st.Do(func(w *World) {
+		if alive(w, e) {
+			w.DelEntity(e)
+		}
+	})
+}
+
+func alive(w *World, e Entity) bool {
+	_, ok := w.Entities[e]
+	return ok
+}
+
+// Commit hands the recorded changes to the SyncWorld, to be applied at the next sync point.
+// The Stage is empty after committing, and can be reused.
+func (st *Stage) Commit() {
+	if len(st.commands) == 0 {
+		return
+	}
+	st.sync.stagesMu.Lock()
+	st.sync.stages = append(st.sync.stages, st.commands)
+	st.sync.stagesMu.Unlock()
+	st.commands = nil
+}
@@ -0,0 +1,101 @@
+package ecs
+
+import (
+	"sync"
+	"testing"
+)
+
+// Run with -race to check the locking.
+func TestSyncWorld_race(t *testing.T) {
+	const Workers = 8
+	const Frames = 50
+
+	w := NewWorld()
+	counter := w.NewComponent()
+	var entities [100]Entity
+	for i := range entities {
+		entities[i] = w.NewEntity()
+		w.SetComp(entities[i], counter, 0)
+	}
+	tag := w.NewComponent()
+	s := NewSyncWorld(w)
+
+	for frame := range Frames {
+		var wg sync.WaitGroup
+		for worker := range Workers {
+			wg.Go(func() {
+				stage := s.Stage()
+				s.Read(func(w *World) {
+					for i, e := range entities {
+						if i%Workers != worker {
+							continue
+						}
+						stage.SetComp(e, counter, *w.GetComp[int](e, counter)+1)
+						if frame%2 == 0 {
+							stage.SetComp(e, tag, frame)
+						} else {
+							stage.DelComp(e, tag)
+						}
+					}
+					w.Query(QueryAll(counter), func(entities []Entity, data []any) {})
+				})
+				stage.Commit()
+			})
+		}
+		// Writers and readers can interleave.
+		wg.Go(func() {
+			s.Write(func(w *World) {
+				e := w.NewEntity()
+				w.SetComp(e, counter, -1)
+				w.DelEntity(e)
+			})
+		})
+		wg.Wait()
+		s.Sync()
+	}
+
+	s.Read(func(w *World) {
+		for _, e := range entities {
+			if got := *w.GetComp[int](e, counter); got != Frames {
+				t.Errorf("counter of %v: %v, want: %v", e, got, Frames)
+			}
+			if w.HasComp(e, tag) {
+				t.Errorf("%v has the tag", e)
+			}
+		}
+	})
+}
+
+func TestStage_deleted(t *testing.T) {
+	w := NewWorld()
+	c := w.NewComponent()
+	e := w.NewEntity()
+	w.SetComp(e, c, 1)
+	other := w.NewEntity()
+	s := NewSyncWorld(w)
+
+	// Two stages delete the same Entity, then change it.
+	for range 2 {
+		st := s.Stage()
+		st.DelEntity(e)
+		st.DelComp(e, c)
+		st.AddComp(e, c)
+		st.SetComp(e, c, 2)
+		st.Commit()
+	}
+	// The commands after them are still applied.
+	st := s.Stage()
+	st.SetComp(other, c, 3)
+	st.Commit()
+	s.Sync()
+
+	if _, ok := w.Entities[e]; ok {
+		t.Error("the Entity isn't deleted")
+	}
+	if got := w.GetComp[int](other, c); got == nil || *got != 3 {
+		t.Error("the commands after the deleted Entity are dropped")
+	}
+	if err := w.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+}
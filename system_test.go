@@ -0,0 +1,122 @@
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+	"testing"
+)
+
+func TestWorld_Progress(t *testing.T) {
+	type Position struct{ x int }
+
+	w := NewWorld()
+	position := w.NewComponent()
+	velocity := w.NewComponent()
+	e := w.NewEntity()
+	w.SetComp(e, position, Position{0})
+	w.SetComp(e, velocity, 2)
+
+	var order []string
+	w.AddSystem("Move", QueryAll(position, velocity), func(entities []Entity, data []any) {
+		order = append(order, "Move")
+		p, v := *data[0].(*[]Position), *data[1].(*[]int)
+		for i := range entities {
+			p[i].x += v[i]
+		}
+	})
+	w.AddSystem("Print", QueryAll(position), func(entities []Entity, data []any) {
+		order = append(order, "Print")
+	})
+
+	w.Progress(context.Background())
+	w.Progress(context.Background())
+	if got := w.GetComp[Position](e, position).x; got != 4 {
+		t.Errorf("position get: %d, want: 4", got)
+	}
+	if len(order) != 4 || order[0] != "Move" || order[1] != "Print" {
+		t.Errorf("systems run in the order: %v", order)
+	}
+	if s := w.Systems(); len(s) != 2 || s[1].Name != "Print" {
+		t.Errorf("Systems get: %v", s)
+	}
+
+	stats := w.Stats().Systems
+	if len(stats) != 2 || stats[0].Name != "Move" || stats[0].Runs != 2 || stats[0].Total < stats[0].Last {
+		t.Errorf("the Stats of Systems get: %+v", stats)
+	}
+}
+
+func TestCachedQuery_Run_traced(t *testing.T) {
+	w := NewWorld()
+	c := w.NewComponent()
+	w.SetComp(w.NewEntity(), c, 1)
+	q := w.Cache(QueryAll(c), QueryName("Named"))
+	if q.Name() != "Named" {
+		t.Errorf("Name get: %q", q.Name())
+	}
+	w.AddSystem("System", QueryAll(c), func(entities []Entity, data []any) {})
+
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skip(err)
+	}
+	count := 0
+	q.Run(func(entities []Entity, data []any) { count += len(entities) })
+	w.Progress(context.Background())
+	trace.Stop()
+	if count != 1 {
+		t.Errorf("Run get: %d entities, want: 1", count)
+	}
+}
+
+// goroutineLabels reports whether the current goroutine has all the pprof labels,
+// which are found in the goroutine profile.
+func goroutineLabels(labels ...string) bool {
+	var buf strings.Builder
+	pprof.Lookup("goroutine").WriteTo(&buf, 1)
+	for record := range strings.SplitSeq(buf.String(), "\n\n") {
+		// Only the current goroutine is running goroutineLabels.
+		if !strings.Contains(record, "goroutineLabels") {
+			continue
+		}
+		for _, l := range labels {
+			if !strings.Contains(record, l) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func TestCachedQuery_RunContext_labels(t *testing.T) {
+	w := NewWorld()
+	c := w.NewComponent()
+	w.SetComp(w.NewEntity(), c, 1)
+	q := w.Cache(QueryAll(c), QueryName("Named"))
+
+	var inQuery, afterQuery bool
+	w.AddSystem("System", QueryAll(c), func(entities []Entity, data []any) {
+		q.Run(func(entities []Entity, data []any) {})
+		// The label of the System is kept after running a named query.
+		afterQuery = goroutineLabels(`"ecs.system":"System"`)
+	})
+	pprof.Do(context.Background(), pprof.Labels("caller", "test"), func(ctx context.Context) {
+		q.RunContext(ctx, func(entities []Entity, data []any) {
+			inQuery = goroutineLabels(`"caller":"test"`, `"ecs.query":"Named"`)
+		})
+		w.Progress(ctx)
+		if !goroutineLabels(`"caller":"test"`) {
+			t.Error("the labels of the caller are lost")
+		}
+	})
+	if !inQuery {
+		t.Error("the query isn't labeled")
+	}
+	if !afterQuery {
+		t.Error("the label of the System is lost")
+	}
+}
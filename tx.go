@@ -0,0 +1,145 @@
+package ecs
+
+import "slices"
+
+// A Tx is a transaction on a World, created by World.Tx.
+// The changes to the World inside the transaction are made through the embedded World as usual.
+type Tx struct {
+	*World
+	// The transaction this one is nested in, or nil.
+	outer *Tx
+	// The rows of the archetypes touched in the transaction, as they were before it.
+	// It's nil for the archetypes which were empty.
+	saved map[*Archetype]*archetypeRows
+}
+
+// Tx calls f in a transaction. If f returns an error or panics, the changes made by f are rolled back,
+// and the World is restored exactly to the state before the call,
+// including the entities, their Components, data, names, the archetypes they belong to and their rows,
+// and the IDManager.
+// The error is returned, and the panic is propagated after the rollback.
+//
+// The data modified in place, through the pointers returned by GetComp or the columns passed to the queries
+// inside the transaction, is also rolled back. For this, the rows of each archetype are copied the first time
+// they are touched in the transaction, so a transaction costs a copy of the archetypes it touches.
+// The writes through the pointers taken before the transaction aren't tracked, and may not be rolled back.
+// The archetypes created in the transaction are left empty,
+// except that the ones having the Components created in the transaction are deleted with the Components.
+//
+// If World.Merge is called in the transaction, the World merged from is also restored.
+//
+// Transactions can be nested. If an inner transaction fails, only its changes are rolled back.
+// If a Journal is recording the World, a successful transaction is recorded as a single transaction named "Tx".
+//
+// A transaction is atomic to the other goroutines if the World is guarded by SyncWorld.Write.
+func (w *World) Tx(f func(tx *Tx) error) (err error) {
+	// Whether f returns normally, which is false if it panics or calls runtime.Goexit.
+	completed := false
+	outer := w.journal
+	j := &Journal{w: w}
+	j.Begin("Tx")
+	ids := IDManager{NextID: w.NextID, Freelist: slices.Clone(w.Freelist)}
+	tx := &Tx{World: w, outer: w.tx, saved: make(map[*Archetype]*archetypeRows)}
+	w.journal, w.tx = j, tx
+
+	defer func() {
+		r := recover()
+		ops := j.current.ops
+		w.journal, w.tx = outer, tx.outer
+		if completed && err == nil {
+			if outer != nil && len(ops) > 0 {
+				outer.record("Tx", ops...)
+			}
+			return
+		}
+		// Put the entities back to their archetypes, then the rows of the archetypes in order.
+		j.replay(func(w *World) {
+			for _, op := range slices.Backward(ops) {
+				op.undo(w)
+			}
+		})
+		tx.restore()
+		w.IDManager = ids
+		if r != nil {
+			panic(r)
+		}
+	}()
+	err = f(tx)
+	completed = true
+	return err
+}
+
+// archetypeRows is a copy of the rows of an archetype.
+type archetypeRows struct {
+	entities Table[Entity]
+	comps    []Storage
+	disabled bitset
+	toggles  []bitset
+}
+
+func (a *Archetype) copyRows() *archetypeRows {
+	n := len(a.entities)
+	rows := &archetypeRows{
+		entities: slices.Clone(a.entities),
+		comps:    make([]Storage, len(a.Comps)),
+		disabled: a.disabled.clone(n),
+		toggles:  make([]bitset, len(a.toggles)),
+	}
+	for i, s := range a.Comps {
+		if s != nil {
+			rows.comps[i] = s.clone(n)
+		}
+	}
+	for i := range a.toggles {
+		rows.toggles[i] = a.toggles[i].clone(n)
+	}
+	return rows
+}
+
+// touch is called before the rows of the archetype are changed, or their data is handed out to be modified,
+// so the running transactions can copy the rows the first time, and restore them when rolled back.
+func (w *World) touch(a *Archetype) {
+	var rows *archetypeRows
+	copied := false
+	for tx := w.tx; tx != nil; tx = tx.outer {
+		if _, ok := tx.saved[a]; ok {
+			// The outer transactions have copied it no later than this one.
+			return
+		}
+		// The empty archetypes are empty again after the entities are put back.
+		if !copied && len(a.entities) > 0 {
+			rows, copied = a.copyRows(), true
+		}
+		tx.saved[a] = rows
+	}
+}
+
+// restore puts the copied rows back to the archetypes.
+// The entities must have been put back to the archetypes they were in.
+func (tx *Tx) restore() {
+	for a, rows := range tx.saved {
+		if rows == nil {
+			continue
+		}
+		// The rows may be shared with the outer transactions, so they are copied again.
+		n := len(rows.entities)
+		a.entities = slices.Clone(rows.entities)
+		a.records = a.records[:0]
+		for row, e := range a.entities {
+			rec := tx.Entities[e]
+			rec.Row = row
+			a.records = append(a.records, rec)
+		}
+		for i, s := range rows.comps {
+			if s != nil {
+				a.Comps[i] = s.clone(n)
+				a.changed[i]++
+			}
+		}
+		a.disabled = rows.disabled.clone(n)
+		for i := range rows.toggles {
+			a.toggles[i] = rows.toggles[i].clone(n)
+		}
+		a.version++
+	}
+}
@@ -0,0 +1,192 @@
+package ecs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestWorld_Tx(t *testing.T) {
+	type Position struct{ X, Y int }
+
+	w := NewWorld()
+	position := w.NewComponent()
+	tag := w.NewComponent()
+	childOf := w.NewComponent()
+	parent := w.NewEntity()
+	e := w.NewEntity()
+	w.SetName(e, "hero")
+	w.SetComp(e, position, Position{1, 2})
+	var others [3]Entity
+	for i := range others {
+		others[i] = w.NewEntity()
+		w.SetComp(others[i], position, Position{i, i})
+	}
+	w.DelEntity(w.NewEntity()) // Leaves an id in the Freelist
+
+	q := w.Cache(QueryAll(position))
+	defer q.Close()
+
+	// The state to be restored
+	before := w.Describe(e) + w.Describe(parent)
+	ids := IDManager{NextID: w.NextID, Freelist: slices.Clone(w.Freelist)}
+	at := w.Entities[e].AT
+	rows := func() map[*Archetype]string {
+		m := make(map[*Archetype]string)
+		for _, a := range w.Archetypes {
+			if len(a.entities) > 0 {
+				m[a] = fmt.Sprint(a.entities, w.Describe(a.entities[len(a.entities)-1]))
+			}
+		}
+		return m
+	}
+	beforeRows := rows()
+
+	errInvalid := errors.New("invalid")
+	changes := func(tx *Tx) {
+		// Modify the data in place.
+		tx.GetComp[Position](e, position).X = 100
+		q.Run(func(entities []Entity, data []any) {
+			for i := range entities {
+				(*data[0].(*[]Position))[i].Y = 100
+			}
+		})
+		// Reorder the rows.
+		tx.DelEntity(others[0])
+		tx.Clone(others[1])
+		tx.SetComp(e, position, Position{3, 4})
+		tx.AddComp(e, tag)
+		tx.AddComp(e, tx.Pair(childOf, parent))
+		tx.DisableComp(e, position)
+		tx.SetName(e, "villain")
+		tx.Disable(parent)
+		for range 3 {
+			tx.SetComp(tx.NewEntity(), position, Position{})
+		}
+		tx.DelEntity(parent)
+	}
+	check := func(t *testing.T) {
+		t.Helper()
+		if got := w.Describe(e) + w.Describe(parent); got != before {
+			t.Errorf("get:\n%s\nwant:\n%s", got, before)
+		}
+		if got := (IDManager{NextID: w.NextID, Freelist: w.Freelist}); !reflect.DeepEqual(got, ids) {
+			t.Errorf("IDManager get: %+v, want: %+v", got, ids)
+		}
+		if got := rows(); !reflect.DeepEqual(got, beforeRows) {
+			t.Errorf("the rows get: %v, want: %v", got, beforeRows)
+		}
+		if w.Entities[e].AT != at || len(w.Pairs) != 0 || q.Count() != 4 {
+			t.Errorf("the archetypes are not restored")
+		}
+		if err := w.CheckInvariants(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	err := w.Tx(func(tx *Tx) error {
+		changes(tx)
+		return errInvalid
+	})
+	if err != errInvalid {
+		t.Errorf("Tx get: %v, want: %v", err, errInvalid)
+	}
+	check(t)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recover get: %v, want: boom", r)
+			}
+		}()
+		w.Tx(func(tx *Tx) error {
+			changes(tx)
+			panic("boom")
+		})
+	}()
+	check(t)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Tx(func(tx *Tx) error {
+			changes(tx)
+			runtime.Goexit()
+			return nil
+		})
+	}()
+	<-done
+	check(t)
+
+	// The failed inner transaction doesn't affect the outer one.
+	err = w.Tx(func(tx *Tx) error {
+		tx.SetComp(e, position, Position{5, 6})
+		tx.Tx(func(tx *Tx) error {
+			changes(tx)
+			return errInvalid
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := *w.GetComp[Position](e, position); got != (Position{5, 6}) || w.Name(e) != "hero" {
+		t.Errorf("get: %v, %q, want: {5 6}, hero", got, w.Name(e))
+	}
+}
+
+func TestWorld_Tx_journal(t *testing.T) {
+	w := NewWorld()
+	c := w.NewComponent()
+	e := w.NewEntity()
+	j := w.StartJournal(0)
+
+	w.Tx(func(tx *Tx) error {
+		tx.SetComp(e, c, 1)
+		tx.SetComp(tx.NewEntity(), c, 2)
+		return nil
+	})
+	w.Tx(func(tx *Tx) error {
+		tx.SetComp(e, c, 3)
+		return errors.New("failed")
+	})
+	if got := *w.GetComp[int](e, c); got != 1 {
+		t.Errorf("get: %d, want: 1", got)
+	}
+	if name, ok := j.Undo(); !ok || name != "Tx" {
+		t.Errorf("Undo get: %q, %v, want: Tx", name, ok)
+	}
+	if w.HasComp(e, c) || len(w.Entities) != 2 {
+		t.Errorf("the transaction isn't undone as a whole")
+	}
+	if _, ok := j.Undo(); ok {
+		t.Errorf("the failed transaction is recorded")
+	}
+}
+
+func TestWorld_Tx_merge(t *testing.T) {
+	src := NewWorld()
+	c := src.NewComponent()
+	src.SetComp(src.NewEntity(), c, 1)
+	count := len(src.Entities)
+
+	w := NewWorld()
+	w.Tx(func(tx *Tx) error {
+		if _, err := tx.Merge(src, nil); err != nil {
+			return err
+		}
+		return errors.New("failed")
+	})
+	if len(w.Entities) != 0 || len(src.Entities) != count {
+		t.Errorf("the entities get: %d in w, %d in src, want: 0, %d", len(w.Entities), len(src.Entities), count)
+	}
+	if err := src.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+	if err := w.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+}
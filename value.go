@@ -0,0 +1,73 @@
+package ecs
+
+import "reflect"
+
+// CompType returns the type of the data of the Component,
+// or nil if the Component is a tag or its data has never been set.
+func (w *World) CompType(c Component) reflect.Type {
+	if t := w.tableType(c); t != nil {
+		// The t is *Table[T], so t.Elem().Elem() is T.
+		return t.Elem().Elem()
+	}
+	return nil
+}
+
+// tableType returns the reflect.Type of *Table[T] of the Component, found in the archetypes having its data.
+func (w *World) tableType(c Component) reflect.Type {
+	for a, col := range w.Components[c] {
+		if col != -1 {
+			return a.Types[col].TableType
+		}
+	}
+	return nil
+}
+
+// SetCompValue is like SetComp, but the type of data is only known at runtime.
+// It's useful for tools like editors and debuggers.
+//
+// A nil data sets the zero value, which is the nil of the data types like interfaces.
+//
+// This function panics if the data type of the Component is unknown, see CompType,
+// the type of data doesn't match it, or the Entity has the Component as a tag.
+func (w *World) SetCompValue(e Entity, c Component, data any) {
+	rec := w.Entities[e]
+	col, ok := w.Components[c][rec.AT]
+	if ok && col == -1 {
+		panic("ecs: the Component is a tag of the Entity")
+	}
+	if w.journal != nil {
+		w.journal.changing("SetCompValue", e, c)
+	}
+	if ok {
+		w.touch(rec.AT)
+		setData(rec.AT.Comps[col].ptr(rec.Row), data)
+		rec.AT.changed[col]++
+		return
+	}
+	storeType := w.tableType(c)
+	if storeType == nil {
+		panic("ecs: the data type of the Component is unknown")
+	}
+	target := w.addTarget(rec.AT, c, storeType)
+	// Move entity to the new archetype
+	row := w.moveEntity(e, target, rec, rec.AT.Types)
+	if rec.Row != len(rec.AT.entities) {
+		rec.AT.records[rec.Row].Row = rec.Row
+	}
+	s := target.Comps[w.Components[c][target]]
+	s.grow(1)
+	setData(s.ptr(row), data)
+
+	rec.AT = target
+	rec.Row = row
+}
+
+// setData sets the data pointed by p, or the zero value if data is nil.
+func setData(p any, data any) {
+	dst := reflect.ValueOf(p).Elem()
+	if data == nil {
+		dst.SetZero()
+		return
+	}
+	dst.Set(reflect.ValueOf(data))
+}
@@ -0,0 +1,44 @@
+package ecs
+
+import "testing"
+
+func TestWorld_SetCompValue(t *testing.T) {
+	w := NewWorld()
+	c := w.NewComponent()
+	e1, e2 := w.NewEntity(), w.NewEntity()
+	w.SetComp(e1, c, "hello")
+	if typ := w.CompType(c); typ == nil || typ.Kind().String() != "string" {
+		t.Fatalf("CompType get: %v, want: string", typ)
+	}
+
+	w.SetCompValue(e1, c, "world")
+	w.SetCompValue(e2, c, "ecs")
+	if *w.GetComp[string](e1, c) != "world" || *w.GetComp[string](e2, c) != "ecs" {
+		t.Errorf("SetCompValue doesn't set the values")
+	}
+
+	tag := w.NewComponent()
+	w.AddComp(e1, tag)
+	if w.CompType(tag) != nil {
+		t.Errorf("CompType of a tag isn't nil")
+	}
+}
+
+func TestWorld_SetCompValue_tag(t *testing.T) {
+	w := NewWorld()
+	c, other := w.NewComponent(), w.NewComponent()
+	e1, e2 := w.NewEntity(), w.NewEntity()
+	// e2 has c as a tag in the archetype (other, c), while e1 has its data.
+	w.AddComp(e2, other)
+	w.AddComp(e2, c)
+	w.SetComp(e1, c, 1)
+	defer func() {
+		if recover() == nil {
+			t.Error("SetCompValue doesn't panic on a tag of the Entity")
+		}
+		if err := w.CheckInvariants(); err != nil {
+			t.Error(err)
+		}
+	}()
+	w.SetCompValue(e2, c, 2)
+}
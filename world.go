@@ -3,6 +3,7 @@ package ecs
 import (
 	"hash/maphash"
 	"reflect"
+	"slices"
 	"sort"
 	"unsafe"
 	"weak"
@@ -42,10 +43,36 @@ type (
 		// otherwise the col is the index of the component's Storage in the archetype.
 		Components map[Component]map[*Archetype]int
 
+		// The Components representing relationships, which are created on demand by World.Pair.
+		// It's read-only, writing to it desyncs it from its reverse.
+		Pairs map[Pair]Component
+		// The reverse of Pairs.
+		pairOf map[Component]Pair
+
+		// The index of the names given by World.SetName.
+		// It's read-only, use World.SetName to change the names.
+		Names map[string]Entity
+		// The reverse of Names.
+		nameOf map[Entity]string
+
 		// For high performance, we cache the queries.
 		// But these caches will get outdated when new archetypes are created.
 		// We register all queries created here, and update them when new archetypes are created.
 		Queries Table[weak.Pointer[CachedQuery]]
+
+		// The systems added by World.AddSystem, run by World.Progress.
+		systems []*System
+
+		// The metadata of the data types of Components, see World.CompMeta.
+		metas map[reflect.Type]*TypeMeta
+
+		// The Journal recording the changes, or nil if not recording.
+		journal *Journal
+		// The innermost running transaction, or nil if not in World.Tx.
+		tx *Tx
+
+		// The number of lookups of ArchetypeEdge which hit or miss, reported by World.Stats.
+		edgeHits, edgeMisses uint64
 	}
 
 	// An Entity is a unique thing in the world, and is represented by a 64-bit id.
@@ -79,6 +106,22 @@ type (
 		records  Table[*EntityRecord]
 		Comps    []Storage
 
+		// Rows of the disabled entities.
+		// They are hidden from queries unless the Filter asks for them.
+		disabled bitset
+		// For each of the Types, rows of the entities whose component is disabled.
+		toggles []bitset
+
+		// For change detection.
+		// The version is increased when the rows are added, removed, or hidden from the queries,
+		// and changed[i] is increased when the data of Comps[i] is changed in place.
+		version uint64
+		changed []uint64
+
+		// The cached queries matching this archetype.
+		// They are told when the archetype becomes empty or non-empty.
+		queries Table[weak.Pointer[CachedQuery]]
+
 		// A list of edges to other archetypes.
 		// Used to find the next archetype when adding or removing Components.
 		edges map[Component]ArchetypeEdge
@@ -103,6 +146,10 @@ type (
 		appendFrom(other Storage, column int)
 		swapDelete(i int)
 		toSlice() any
+		slice(i, j int) any
+		grow(n int)
+		ptr(i int) any
+		clone(n int) Storage
 
 		Get(i int) any
 	}
@@ -115,6 +162,11 @@ func NewWorld() (w *World) {
 		Entities:   make(map[Entity]*EntityRecord),
 		Archetypes: make(map[uint64]*Archetype),
 		Components: make(map[Component]map[*Archetype]int),
+		Pairs:      make(map[Pair]Component),
+		pairOf:     make(map[Component]Pair),
+		Names:      make(map[string]Entity),
+		nameOf:     make(map[Entity]string),
+		metas:      make(map[reflect.Type]*TypeMeta),
 	}
 	w.Zero = w.newArchetype(Types(nil), Types(nil).sortHash(&w.hash))
 	return
@@ -125,25 +177,27 @@ func (w *World) NewEntity() (e Entity) {
 	e = Entity(w.get())
 	r := new(EntityRecord)
 	r.AT = w.Zero
-	r.Row = w.Zero.entities.append(e)
-	w.Zero.records.append(r)
+	w.touch(w.Zero)
+	r.Row = w.Zero.push(e, r)
 	w.Entities[e] = r
+	if w.journal != nil {
+		w.journal.created("NewEntity", e)
+	}
 	return
 }
 
 func (w *World) DelEntity(e Entity) {
-	rec := w.Entities[e]
-	rec.AT.entities.swapDelete(rec.Row)
-	rec.AT.records.swapDelete(rec.Row)
-	for _, s := range rec.AT.Comps {
-		if s != nil {
-			s.swapDelete(rec.Row)
-		}
+	if w.journal != nil {
+		w.journal.deleting(e)
 	}
+	rec := w.Entities[e]
+	w.touch(rec.AT)
+	rec.AT.swapDelete(rec.Row)
 	if rec.Row != len(rec.AT.entities) {
 		rec.AT.records[rec.Row].Row = rec.Row
 	}
 	delete(w.Entities, e)
+	w.delName(e)
 	w.IDManager.put(uint64(e))
 }
 
@@ -159,9 +213,11 @@ func (w *World) NewComponent() (c Component) {
 // We Always calculate it before calling this function, so just pass it in.
 func (w *World) newArchetype(t Types, hash uint64) (a *Archetype) {
 	a = &Archetype{
-		Types: t,
-		Comps: make([]Storage, len(t)),
-		edges: make(map[Component]ArchetypeEdge),
+		Types:   t,
+		Comps:   make([]Storage, len(t)),
+		toggles: make([]bitset, len(t)),
+		changed: make([]uint64, len(t)),
+		edges:   make(map[Component]ArchetypeEdge),
 	}
 	for i, v := range t {
 		if v.TableType != nil {
@@ -173,22 +229,62 @@ func (w *World) newArchetype(t Types, hash uint64) (a *Archetype) {
 	}
 	w.Archetypes[hash] = a
 
-	// update queries
-	var deleteList []int
-	for i, q := range w.Queries {
-		if q := q.Value(); q != nil {
+	// update queries, and remove the ones garbage collected without being closed.
+	// Iterate backwards, so the one swapped into place i has been visited.
+	for i := len(w.Queries) - 1; i >= 0; i-- {
+		if q := w.Queries[i].Value(); q != nil {
 			q.update(w, a)
 		} else {
-			deleteList = append(deleteList, i)
+			w.Queries.swapDelete(i)
 		}
 	}
-	for _, i := range deleteList {
-		w.Queries.swapDelete(i)
-	}
 
 	return
 }
 
+// archetype finds the archetype of the Types, or creates it if it doesn't exist.
+// The Types will be sorted.
+func (w *World) archetype(t Types) *Archetype {
+	hash := t.sortHash(&w.hash)
+	if a, ok := w.Archetypes[hash]; ok {
+		return a
+	}
+	return w.newArchetype(t, hash)
+}
+
+// delArchetype deletes the empty archetype a from the World,
+// which is used when the Components of a are unregistered.
+func (w *World) delArchetype(a *Archetype) {
+	delete(w.Archetypes, slices.Clone(a.Types).sortHash(&w.hash))
+	for _, t := range a.Types {
+		delete(w.Components[t.Component], a)
+	}
+	// Remove the edges leading to a.
+	for _, b := range w.Archetypes {
+		for c, edge := range b.edges {
+			if edge.add != a && edge.del != a {
+				continue
+			}
+			if edge.add == a {
+				edge.add = nil
+			}
+			if edge.del == a {
+				edge.del = nil
+			}
+			if edge.add == nil && edge.del == nil {
+				delete(b.edges, c)
+			} else {
+				b.edges[c] = edge
+			}
+		}
+	}
+	for _, p := range a.queries {
+		if q := p.Value(); q != nil {
+			q.remove(a)
+		}
+	}
+}
+
 // AddComp adds the Component to Entity as a tag, without underlying content
 func (w *World) AddComp(e Entity, c Component) {
 	rec := w.Entities[e]
@@ -197,23 +293,12 @@ func (w *World) AddComp(e Entity, c Component) {
 	if _, ok := w.Components[c][rec.AT]; ok {
 		return
 	}
-	// Lookup ArchetypeEdge for shortcuts
-	edge := rec.AT.edges[c]
-	target := edge.add
-	if target == nil {
-		// We don't have shortcuts yet. Use the hash way.
-		var ok bool
-		newTypes := rec.AT.Types.copyAppend(c, nil)
-		hash := newTypes.sortHash(&w.hash)
-		if target, ok = w.Archetypes[hash]; !ok {
-			target = w.newArchetype(newTypes, hash)
-		}
-		// Save to the shortcuts
-		edge.add = target
-		rec.AT.edges[c] = edge
+	if w.journal != nil {
+		w.journal.changing("AddComp", e, c)
 	}
+	target := w.addTarget(rec.AT, c, nil)
 	// Move entity to the new archetype
-	row := moveEntity(e, target, rec, rec.AT.Types)
+	row := w.moveEntity(e, target, rec, rec.AT.Types)
 	// Because we move the last entity in rec.AT.entities.
 	// We have to update its Row value in w.entities.
 	if rec.Row != len(rec.AT.entities) {
@@ -224,6 +309,29 @@ func (w *World) AddComp(e Entity, c Component) {
 	rec.Row = row
 }
 
+// addTarget returns the archetype which has all Components of a and the Component c.
+// The storeType is the reflect.Type of *Table[T] for the data of c, or nil if c is a tag.
+func (w *World) addTarget(a *Archetype, c Component, storeType reflect.Type) *Archetype {
+	// Lookup ArchetypeEdge for shortcuts
+	edge := a.edges[c]
+	if edge.add != nil {
+		w.edgeHits++
+	} else {
+		w.edgeMisses++
+		// We don't have shortcuts yet. Use the hash way.
+		newTypes := a.Types.copyAppend(c, storeType)
+		hash := newTypes.sortHash(&w.hash)
+		target, ok := w.Archetypes[hash]
+		if !ok {
+			target = w.newArchetype(newTypes, hash)
+		}
+		// Save to the shortcuts
+		edge.add = target
+		a.edges[c] = edge
+	}
+	return edge.add
+}
+
 // HasComp reports whether the Entity has the Component.
 func (w *World) HasComp(e Entity, c Component) bool {
 	rec := w.Entities[e]
@@ -238,30 +346,21 @@ func (w *World) HasComp(e Entity, c Component) bool {
 //
 // This function panics if the type of data doesn't match others of the same Component.
 func (w *World) SetComp[C any](e Entity, c Component, data C) {
+	if w.journal != nil {
+		w.journal.changing("SetComp", e, c)
+	}
 	rec := w.Entities[e]
 	// If the archetype of e already contains c.
 	// Override the data and return.
 	if col, ok := w.Components[c][rec.AT]; ok {
+		w.touch(rec.AT)
 		(*rec.AT.Comps[col].(*Table[C]))[rec.Row] = data
+		rec.AT.changed[col]++
 		return
 	}
-	// Lookup ArchetypeEdge for shortcuts
-	edge := rec.AT.edges[c]
-	target := edge.add
-	if target == nil {
-		// We don't have shortcuts yet. Use the hash way.
-		var ok bool
-		newTypes := rec.AT.Types.copyAppend(c, reflect.TypeFor[*Table[C]]())
-		hash := newTypes.sortHash(&w.hash)
-		if target, ok = w.Archetypes[hash]; !ok {
-			target = w.newArchetype(newTypes, hash)
-		}
-		// Save to the shortcuts
-		edge.add = target
-		rec.AT.edges[c] = edge
-	}
+	target := w.addTarget(rec.AT, c, reflect.TypeFor[*Table[C]]())
 	// Move entity to the new archetype
-	row := moveEntity(e, target, rec, rec.AT.Types)
+	row := w.moveEntity(e, target, rec, rec.AT.Types)
 	// Because we move the last entity in rec.AT.entities.
 	// We have to update its Row value in w.entities.
 	if rec.Row != len(rec.AT.entities) {
@@ -277,16 +376,23 @@ func (w *World) SetComp[C any](e Entity, c Component, data C) {
 // If the Entity doesn't have the Component, nothing will happen.
 func (w *World) DelComp(e Entity, c Component) {
 	rec := w.Entities[e]
-	col, ok := w.Components[c][rec.AT]
+	_, ok := w.Components[c][rec.AT]
 	if !ok {
 		return // archetype of e doesn't contain component c
 	}
+	if w.journal != nil {
+		w.journal.changing("DelComp", e, c)
+	}
 	// Lookup ArchetypeEdge for shortcuts
 	edge := rec.AT.edges[c]
 	target := edge.del
-	if target == nil {
+	if target != nil {
+		w.edgeHits++
+	} else {
+		w.edgeMisses++
 		// We don't have shortcuts yet. Use the hash way.
-		newTypes := rec.AT.Types.copyDelete(col)
+		// The column is -1 for tags, so find the index in Types instead.
+		newTypes := rec.AT.Types.copyDelete(rec.AT.index(c))
 		hash := newTypes.sortHash(&w.hash)
 		if target, ok = w.Archetypes[hash]; !ok {
 			target = w.newArchetype(newTypes, hash)
@@ -296,7 +402,7 @@ func (w *World) DelComp(e Entity, c Component) {
 		rec.AT.edges[c] = edge
 	}
 	// Move entity
-	row := moveEntity(e, target, rec, target.Types)
+	row := w.moveEntity(e, target, rec, target.Types)
 	// Because we move the last entity in rec.AT.entities.
 	// We have to update its Row value in w.entities.
 	if rec.Row != len(rec.AT.entities) {
@@ -307,7 +413,9 @@ func (w *World) DelComp(e Entity, c Component) {
 	rec.Row = row
 }
 
-func moveEntity(e Entity, dst *Archetype, srcRec *EntityRecord, list Types) (newRow int) {
+func (w *World) moveEntity(e Entity, dst *Archetype, srcRec *EntityRecord, list Types) (newRow int) {
+	w.touch(srcRec.AT)
+	w.touch(dst)
 	// Copy Components
 	srcCol, dstCol := 0, 0
 	for _, t := range list {
@@ -323,18 +431,63 @@ func moveEntity(e Entity, dst *Archetype, srcRec *EntityRecord, list Types) (new
 		if src := srcRec.AT.Comps[srcCol]; src != nil {
 			dst.Comps[dstCol].appendFrom(src, srcRec.Row)
 		}
+		if srcRec.AT.toggles[srcCol].has(srcRec.Row) {
+			dst.toggles[dstCol].set(len(dst.entities))
+		}
+	}
+	newRow = dst.push(e, srcRec)
+	if srcRec.AT.disabled.has(srcRec.Row) {
+		dst.disabled.set(newRow)
 	}
 	// Delete everything in src
-	newRow = dst.entities.append(e)
-	dst.records.append(srcRec)
-	srcRec.AT.entities.swapDelete(srcRec.Row)
-	srcRec.AT.records.swapDelete(srcRec.Row)
-	for _, s := range srcRec.AT.Comps {
+	srcRec.AT.swapDelete(srcRec.Row)
+	return
+}
+
+// push appends a row of the Entity to the archetype, and returns the index of the row.
+// The caller is responsible for appending the data to the columns.
+func (a *Archetype) push(e Entity, r *EntityRecord) (row int) {
+	row = a.entities.append(e)
+	a.records.append(r)
+	a.version++
+	if row == 0 {
+		a.activate(true)
+	}
+	return
+}
+
+// swapDelete removes a row from the archetype by moving the last row into its place.
+// The caller is responsible for updating the Row of the moved entity's record.
+func (a *Archetype) swapDelete(row int) {
+	last := len(a.entities) - 1
+	a.version++
+	a.entities.swapDelete(row)
+	a.records.swapDelete(row)
+	for _, s := range a.Comps {
 		if s != nil {
-			s.swapDelete(srcRec.Row)
+			s.swapDelete(row)
 		}
 	}
-	return
+	a.disabled.swapDelete(row, last)
+	for i := range a.toggles {
+		a.toggles[i].swapDelete(row, last)
+	}
+	if last == 0 {
+		a.activate(false)
+	}
+}
+
+// activate tells the cached queries matching the archetype that it becomes non-empty, or empty if active is false.
+func (a *Archetype) activate(active bool) {
+	for i := 0; i < len(a.queries); {
+		q := a.queries[i].Value()
+		if q == nil {
+			a.queries.swapDelete(i)
+			continue
+		}
+		q.activate(a, active)
+		i++
+	}
 }
 
 // GetComp gets the data of a Component of an Entity.
@@ -342,11 +495,22 @@ func moveEntity(e Entity, dst *Archetype, srcRec *EntityRecord, list Types) (new
 func (w *World) GetComp[C any](e Entity, c Component) (data *C) {
 	rec := w.Entities[e]
 	if column, ok := w.Components[c][rec.AT]; ok {
+		w.touch(rec.AT)
 		return &(*rec.AT.Comps[column].(*Table[C]))[rec.Row]
 	}
 	return nil
 }
 
+// Modified tells the change detection that the data of the Component of the Entity is changed,
+// which is necessary if the data is modified through the pointer returned by GetComp, or in the queries.
+// SetComp does this automatically.
+func (w *World) Modified(e Entity, c Component) {
+	rec := w.Entities[e]
+	if col, ok := w.Components[c][rec.AT]; ok && col != -1 {
+		rec.AT.changed[col]++
+	}
+}
+
 // get an ID from the IDManager.
 // If the Freelist isn't empty, the ID is obtained there, otherwise it's generated incrementally.
 func (i *IDManager) get() (id uint64) {
@@ -414,10 +578,35 @@ func (c *Table[C]) toSlice() any {
 	return (*[]C)(c)
 }
 
+// slice returns the rows [i, j) in the same form as toSlice.
+// The capacity is limited, so appending to it won't overwrite the following rows.
+func (c *Table[C]) slice(i, j int) any {
+	s := []C((*c)[i:j:j])
+	return &s
+}
+
+// clone returns a copy of the first n rows.
+func (c *Table[C]) clone(n int) Storage {
+	t := Table[C](slices.Clone((*c)[:n]))
+	return &t
+}
+
 func (c *Table[C]) Get(i int) any {
 	return (*c)[i]
 }
 
+// ptr returns the pointer to the i-th data, which is of type *C.
+func (c *Table[C]) ptr(i int) any {
+	return &(*c)[i]
+}
+
+// grow appends n zero values to the Table.
+func (c *Table[C]) grow(n int) {
+	l := len(*c)
+	*c = slices.Grow(*c, n)[:l+n]
+	clear((*c)[l:])
+}
+
 func (c *Table[C]) append(data C) int {
 	*c = append(*c, data)
 	return len(*c) - 1
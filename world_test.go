@@ -62,6 +62,23 @@ func TestNewEntity(t *testing.T) {
 	}
 }
 
+func TestWorld_DelComp_tag(t *testing.T) {
+	w := NewWorld()
+	tag := w.NewComponent()
+	data := w.NewComponent()
+	e := w.NewEntity()
+	w.AddComp(e, tag)
+	w.SetComp(e, data, 42)
+
+	w.DelComp(e, tag)
+	if w.HasComp(e, tag) || !w.HasComp(e, data) {
+		t.Fatalf("after DelComp of the tag, has tag: %v, has data: %v", w.HasComp(e, tag), w.HasComp(e, data))
+	}
+	if got := *w.GetComp[int](e, data); got != 42 {
+		t.Errorf("data get: %d, want: 42", got)
+	}
+}
+
 func TestDelEntity(t *testing.T) {
 	w := NewWorld()
 